@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/fatih/color"
 	jsoniter "github.com/json-iterator/go"
 )
@@ -29,3 +33,35 @@ func printPrettyJSON(print colorPrint, value interface{}) error {
 	print(string(bytes))
 	return nil
 }
+
+// parseByteSize parses a size with an optional binary unit suffix. (e.g. 512K, 5M, 1.5GiB)
+func parseByteSize(size string) (int64, error) {
+	value := strings.TrimSpace(size)
+	if value == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		scale  float64
+	}{
+		{"g", 1 << 30},
+		{"m", 1 << 20},
+		{"k", 1 << 10},
+		{"", 1},
+	}
+
+	value = strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(value), "b"), "i")
+	for _, unit := range units {
+		if !strings.HasSuffix(value, unit.suffix) {
+			continue
+		}
+
+		num, err := strconv.ParseFloat(strings.TrimSuffix(value, unit.suffix), 64)
+		if err != nil || num < 0 {
+			return 0, fmt.Errorf("malformed size: %s", size)
+		}
+		return int64(num * unit.scale), nil
+	}
+	return 0, fmt.Errorf("malformed size: %s", size)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/iwaltgen/github-dl/pkg/platform"
+)
+
+// aliasesCmd represents the aliases command
+var aliasesCmd = &cobra.Command{
+	Use:   "aliases",
+	Short: "Show the effective os and arch keyword aliases.",
+	Long: `Show the effective os and arch keyword aliases.
+The built-in aliases are merged with the aliases of the config file, and then the flags.
+
+Example:
+github-dl aliases
+github-dl aliases --os-alias "darwin:apple-darwin" --arch-alias "arm64:m1"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		osTable, archTable, err := aliasTables()
+		if err != nil {
+			return err
+		}
+
+		return printPrettyJSON(Cyan, &aliasResult{
+			OS:   aliasEntries(osTable),
+			Arch: aliasEntries(archTable),
+		})
+	},
+}
+
+type aliasResult struct {
+	OS   []*aliasEntry `json:"os"`
+	Arch []*aliasEntry `json:"arch"`
+}
+
+type aliasEntry struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// aliasEntries returns the entries of the table sorted by the name.
+func aliasEntries(table map[string][]string) []*aliasEntry {
+	ret := make([]*aliasEntry, 0, len(table))
+	for k, v := range table {
+		ret = append(ret, &aliasEntry{Name: k, Aliases: v})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// aliasTables returns the effective os and arch alias tables.
+// the config and flag aliases are merged over the built-in aliases in order.
+func aliasTables() (osTable, archTable map[string][]string, err error) {
+	osFlag, err := parseAlias(osAlias)
+	if err != nil {
+		return nil, nil, errors.New("parse alias error: see flags --os-alias")
+	}
+
+	archFlag, err := parseAlias(archAlias)
+	if err != nil {
+		return nil, nil, errors.New("parse alias error: see flags --arch-alias")
+	}
+
+	archOf := func(keyword string) string {
+		v, _ := platform.Arch(keyword)
+		return v
+	}
+
+	osTable, archTable = platform.Aliases()
+	for _, aliases := range []map[string][]string{conf.Aliases.OS, osFlag} {
+		mergeAliases(osTable, aliases, platform.OS)
+	}
+	for _, aliases := range []map[string][]string{conf.Aliases.Arch, archFlag} {
+		mergeAliases(archTable, aliases, archOf)
+	}
+	return osTable, archTable, nil
+}
+
+// mergeAliases appends the lower case aliases to the table without duplicates.
+// keys are normalized by the function. (e.g. macos to darwin)
+func mergeAliases(table, aliases map[string][]string, normalize func(string) string) {
+	for k, values := range aliases {
+		key := normalize(strings.TrimSpace(k))
+		for _, v := range values {
+			v = strings.ToLower(strings.TrimSpace(v))
+			if v != "" && !containsString(table[key], v) {
+				table[key] = append(table[key], v)
+			}
+		}
+	}
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// addAliasFlags adds flags of the os and arch keyword aliases to the command.
+func addAliasFlags(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&osAlias, "os-alias", osAlias,
+		"os keyword aliases merged over the config and built-in aliases, e.g. darwin:macos,osx;windows:win (optional)")
+	flagSet.StringVar(&archAlias, "arch-alias", archAlias,
+		"arch keyword aliases merged over the config and built-in aliases, e.g. amd64:x86_64 (optional)")
+}
+
+func init() {
+	rootCmd.AddCommand(aliasesCmd)
+
+	addAliasFlags(aliasesCmd.Flags())
+}
@@ -0,0 +1,201 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
+	"github.com/reactivex/rxgo/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/iwaltgen/github-dl/pkg/github"
+)
+
+// artifactCmd represents the artifact command
+var artifactCmd = &cobra.Command{
+	Use:   "artifact",
+	Short: "List and download github actions workflow run artifacts.",
+	Long: `List and download github actions workflow run artifacts.
+The latest successful run of the workflow is used, unless a run id is given.
+
+Example:
+github-dl --repo cli/cli artifact list --workflow nightly.yml --branch trunk
+github-dl --repo cli/cli artifact download --workflow nightly.yml --name linux --pick gh --dest bin
+github-dl --repo cli/cli artifact download --run-id 123456789 --dest dist`,
+}
+
+// artifactListCmd represents the artifact list command
+var artifactListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List artifacts of a workflow run.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signalContext()
+		defer cancel()
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		run, artifacts, err := client.ListArtifacts(ctx, github.Repository(repo), &artifactOpt)
+		if err != nil {
+			return err
+		}
+
+		if verbose {
+			color.Cyan("repository:\t%s", repo)
+			color.Cyan("workflow run:\t%d (%s)", run.GetID(), run.GetHTMLURL())
+			return printPrettyJSON(Cyan, artifacts)
+		}
+
+		var results []*runArtifact
+		for _, v := range artifacts {
+			results = append(results, &runArtifact{
+				ID:      v.GetID(),
+				Name:    v.GetName(),
+				Size:    v.GetSizeInBytes(),
+				Expired: v.GetExpired(),
+			})
+		}
+		return printPrettyJSON(Cyan, results)
+	},
+}
+
+// artifactDownloadCmd represents the artifact download command
+var artifactDownloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download artifacts of a workflow run.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signalContext()
+		defer cancel()
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		opt, err := makeInstallOptions()
+		if err != nil {
+			color.Magenta(err.Error())
+			fmt.Println(cmd.UsageString())
+			os.Exit(1)
+		}
+
+		// keep the standard output clean for the artifact stream.
+		if dest == github.StdoutPath {
+			color.Output = colorable.NewColorableStderr()
+		}
+
+		run, artifacts, err := client.ListArtifacts(ctx, github.Repository(repo), &artifactOpt)
+		if err != nil {
+			return err
+		}
+
+		artifacts, err = selectArtifacts(artifacts, artifactNames)
+		if err != nil {
+			return err
+		}
+		if len(artifacts) > 1 && (dest == github.StdoutPath || target != "") {
+			return errors.New("several artifacts can not use --target or stdout: see flags --name")
+		}
+
+		if verbose {
+			color.Cyan("repository:\t%s", repo)
+			color.Cyan("workflow run:\t%d (%s)", run.GetID(), run.GetHTMLURL())
+		}
+
+		// a failed download cancels the others.
+		dlCtx, dlCancel := context.WithCancel(ctx)
+		defer dlCancel()
+
+		var assets []*github.ReleaseAsset
+		var observables []rxgo.Observable
+		for _, artifact := range artifacts {
+			asset, observable, err := client.DownloadArtifact(dlCtx, github.Repository(repo), artifact, opt)
+			if err != nil {
+				return err
+			}
+			assets = append(assets, asset)
+			observables = append(observables, observable)
+		}
+
+		return showDownloadProgress(ctx, dlCancel, assets, rxgo.Merge(observables))
+	},
+}
+
+type runArtifact struct {
+	ID      int64  `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Expired bool   `json:"expired,omitempty"`
+}
+
+var (
+	artifactOpt   github.ArtifactOptions
+	artifactNames []string
+)
+
+// selectArtifacts returns the artifacts of the names, or all artifacts if names are empty.
+func selectArtifacts(artifacts []*github.Artifact, names []string) ([]*github.Artifact, error) {
+	if len(artifacts) == 0 {
+		return nil, errors.New("not found artifact in the workflow run")
+	}
+	if len(names) == 0 {
+		return artifacts, nil
+	}
+
+	var ret []*github.Artifact
+	for _, name := range names {
+		var found *github.Artifact
+		for _, v := range artifacts {
+			if v.GetName() == name {
+				found = v
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("not found artifact: %s", name)
+		}
+		ret = append(ret, found)
+	}
+	return ret, nil
+}
+
+func addArtifactFlags(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&artifactOpt.Workflow, "workflow", artifactOpt.Workflow, "workflow file name or id")
+	flagSet.StringVar(&artifactOpt.Branch, "branch", artifactOpt.Branch, "branch of the workflow run (optional)")
+	flagSet.Int64Var(&artifactOpt.RunID, "run-id", artifactOpt.RunID, "workflow run id instead of the latest successful run (optional)")
+}
+
+func init() {
+	rootCmd.AddCommand(artifactCmd)
+	artifactCmd.AddCommand(artifactListCmd, artifactDownloadCmd)
+
+	addArtifactFlags(artifactListCmd.Flags())
+
+	flagSet := artifactDownloadCmd.Flags()
+	addArtifactFlags(flagSet)
+	flagSet.StringArrayVar(&artifactNames, "name", artifactNames, "artifact name, repeatable (default: all artifacts of the run)")
+	addInstallFlags(flagSet)
+	addArchiveFlags(flagSet)
+}
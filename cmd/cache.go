@@ -0,0 +1,124 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/iwaltgen/github-dl/pkg/cache"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the release asset download cache.",
+	Long: `Manage the release asset download cache.
+
+Example:
+github-dl cache list
+github-dl cache prune --cache-max-size 500M
+github-dl cache clear`,
+}
+
+// cacheListCmd represents the cache list command
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached release assets.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+
+		entries, err := c.List()
+		if err != nil {
+			return err
+		}
+
+		if verbose {
+			size, err := c.Size()
+			if err != nil {
+				return err
+			}
+			color.Cyan("cache dir:\t%s", c.Dir())
+			color.Cyan("cache size:\t%s", pb.Full.New(0).Set(pb.Bytes, true).Format(size))
+		}
+
+		return printPrettyJSON(Cyan, entries)
+	},
+}
+
+// cachePruneCmd represents the cache prune command
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove least recently used assets over the cache size cap.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+
+		maxSize, err := parseByteSize(cacheMaxSize)
+		if err != nil {
+			return errors.New("parse size error: see flags --cache-max-size")
+		}
+
+		removed, err := c.Prune(maxSize)
+		if err != nil {
+			return err
+		}
+
+		return printPrettyJSON(Cyan, removed)
+	},
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached assets.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+
+		if err := c.Clear(); err != nil {
+			return err
+		}
+
+		if verbose {
+			color.Cyan("cache cleared:\t%s", c.Dir())
+		}
+		return nil
+	},
+}
+
+func openCache() (*cache.Cache, error) {
+	if cacheDir == "" {
+		return nil, errors.New("require cache folder: see flags --cache-dir")
+	}
+	return newCache()
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheListCmd, cachePruneCmd, cacheClearCmd)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultHost = "github.com"
+
+// config is the configuration file contents. (YAML, or TOML with the .toml extension)
+//
+//	host: github.example.com
+//	hosts:
+//	  github.example.com:
+//	    token-env: GHE_TOKEN
+//	aliases:
+//	  os:
+//	    darwin: [macos, osx]
+//	  arch:
+//	    amd64: [x86_64]
+type config struct {
+	Host    string                 `yaml:"host" toml:"host"`
+	Hosts   map[string]*hostConfig `yaml:"hosts" toml:"hosts"`
+	Aliases aliasConfig            `yaml:"aliases" toml:"aliases"`
+}
+
+// hostConfig is the per host configuration.
+type hostConfig struct {
+	Token    string `yaml:"token" toml:"token"`
+	TokenEnv string `yaml:"token-env" toml:"token-env"`
+}
+
+// aliasConfig is the os and arch keyword aliases, which are merged over the built-in aliases.
+type aliasConfig struct {
+	OS   map[string][]string `yaml:"os" toml:"os"`
+	Arch map[string][]string `yaml:"arch" toml:"arch"`
+}
+
+var (
+	configPath string
+	conf       = &config{}
+)
+
+func defaultConfigPath() string {
+	if v := os.Getenv("GITHUB_DL_CONFIG"); v != "" {
+		return v
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "github-dl", "config.yaml")
+}
+
+// loadConfig reads the configuration file.
+// a missing file is an error only if it is required.
+func loadConfig(path string, required bool) (*config, error) {
+	ret := &config{}
+	if path == "" {
+		return ret, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config error: %w", err)
+	}
+
+	unmarshal := yaml.Unmarshal
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		unmarshal = toml.Unmarshal
+	}
+	if err := unmarshal(data, ret); err != nil {
+		return nil, fmt.Errorf("parse config `%s` error: %w", path, err)
+	}
+	return ret, nil
+}
+
+// githubHost returns the github host. (flag > environment > config)
+func githubHost() string {
+	if host != "" {
+		return host
+	}
+	if v := os.Getenv("GH_HOST"); v != "" {
+		return v
+	}
+	if conf.Host != "" {
+		return conf.Host
+	}
+	return defaultHost
+}
+
+// hostName returns the host name part of a host or url.
+func hostName(host string) string {
+	name := host
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+// enterpriseURL returns the base url of the GitHub Enterprise Server.
+// it returns empty for github.com.
+func enterpriseURL(host string) string {
+	switch hostName(host) {
+	case defaultHost, "api." + defaultHost:
+		return ""
+	}
+
+	if strings.Contains(host, "://") {
+		return host
+	}
+	return "https://" + host + "/"
+}
+
+// githubToken returns the token of the host.
+// (flag > token environment flag > host config > default environment)
+func githubToken() string {
+	if token != "" {
+		return token
+	}
+	if tokenEnv != "" {
+		return os.Getenv(tokenEnv)
+	}
+
+	host := githubHost()
+	if hc, ok := conf.Hosts[hostName(host)]; ok && hc != nil {
+		if hc.Token != "" {
+			return hc.Token
+		}
+		if hc.TokenEnv != "" {
+			return os.Getenv(hc.TokenEnv)
+		}
+	}
+
+	if enterpriseURL(host) != "" {
+		return os.Getenv("GITHUB_ENTERPRISE_TOKEN")
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
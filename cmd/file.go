@@ -0,0 +1,102 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
+	"github.com/reactivex/rxgo/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/iwaltgen/github-dl/pkg/github"
+)
+
+// fileCmd represents the file command
+var fileCmd = &cobra.Command{
+	Use:   "file <path>...",
+	Short: "Download github repository files at a ref.",
+	Long: `Download github repository files at a ref.
+A folder path downloads the files of the folder recursively.
+
+Example:
+github-dl --repo cli/cli file script/install.sh --ref v1.0.0
+github-dl --repo protocolbuffers/protobuf file src/google/protobuf/timestamp.proto --dest proto
+github-dl --repo cli/cli file docs --ref trunk --target cli-docs
+github-dl --repo cli/cli file script/install.sh --dest - | sh`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signalContext()
+		defer cancel()
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		opt, err := makeInstallOptions()
+		if err != nil {
+			color.Magenta(err.Error())
+			fmt.Println(cmd.UsageString())
+			os.Exit(1)
+		}
+		if len(args) > 1 && (dest == github.StdoutPath || target != "") {
+			return errors.New("several paths can not use --target or stdout")
+		}
+
+		// keep the standard output clean for the file stream.
+		if dest == github.StdoutPath {
+			color.Output = colorable.NewColorableStderr()
+		}
+
+		if verbose {
+			color.Cyan("repository:\t%s", repo)
+			color.Cyan("ref:\t\t%s", fileRef)
+		}
+
+		// a failed download cancels the others.
+		dlCtx, dlCancel := context.WithCancel(ctx)
+		defer dlCancel()
+
+		var assets []*github.ReleaseAsset
+		var observables []rxgo.Observable
+		for _, fpath := range args {
+			asset, observable, err := client.DownloadFile(dlCtx, github.Repository(repo), fileRef, fpath, opt)
+			if err != nil {
+				return err
+			}
+			assets = append(assets, asset)
+			observables = append(observables, observable)
+		}
+
+		return showDownloadProgress(ctx, dlCancel, assets, rxgo.Merge(observables))
+	},
+}
+
+var fileRef string
+
+func init() {
+	rootCmd.AddCommand(fileCmd)
+
+	flagSet := fileCmd.Flags()
+	flagSet.StringVar(&fileRef, "ref", fileRef, "tag, branch or commit (default: the default branch)")
+	addInstallFlags(flagSet)
+}
@@ -17,8 +17,6 @@ limitations under the License.
 package cmd
 
 import (
-	"context"
-
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
@@ -35,8 +33,13 @@ Example:
 github-dl --repo iwaltgen/github-dl list
 github-dl --repo iwaltgen/github-dl list --page 1 --per-page 10`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ctx := context.Background()
-		client := github.NewClient(githubToken(), verbose)
+		ctx, cancel := signalContext()
+		defer cancel()
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
 		opt := &github.ListOptions{
 			Page:    page,
 			PerPage: perPage,
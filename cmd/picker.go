@@ -0,0 +1,188 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+
+	"github.com/iwaltgen/github-dl/pkg/github"
+)
+
+// stdinTerminal reports whether the standard input is a terminal.
+func stdinTerminal() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// assetReplace is the asset flags of a picked asset, which replace the flags of the options.
+type assetReplace struct {
+	// name is the replaced --asset keyword.
+	name string
+	// flags are the replaced pattern or template flags.
+	flags []string
+	args  []string
+}
+
+// pickAssets asks to pick an asset of the release for each failed selection,
+// and prints the command line which selects the picked assets without a prompt.
+func pickAssets(release *github.RepositoryRelease,
+	resolutions []*github.Resolution,
+	opts []*github.AssetOptions,
+) error {
+	reader := bufio.NewReader(os.Stdin)
+	var replaces []*assetReplace
+	for i, resolution := range resolutions {
+		if resolution.Err == nil {
+			continue
+		}
+
+		color.Yellow(resolution.Error)
+		asset, err := pickAsset(reader, resolution)
+		if err != nil {
+			return err
+		}
+		resolution.Select(asset)
+		replaces = append(replaces, replaceAsset(release, opts[i], asset))
+	}
+
+	if len(replaces) > 0 {
+		color.Cyan("command line:\t%s", commandLine(os.Args, replaces))
+	}
+	return nil
+}
+
+// pickAsset lists the candidates of the resolution, and reads the number of the picked one.
+// An empty answer or the end of input returns the selection error.
+func pickAsset(reader *bufio.Reader, resolution *github.Resolution) (*github.ReleaseAsset, error) {
+	size := pb.Full.New(0).Set(pb.Bytes, true)
+	for i, v := range resolution.Candidates {
+		line := fmt.Sprintf("%3d) %-50s %10s  %s", i+1, v.Name, size.Format(int64(v.Size)), formatCriteria(v.Criteria))
+		if v.Rejected {
+			color.Yellow(line)
+		} else {
+			color.White(line)
+		}
+	}
+
+	for {
+		fmt.Fprintf(color.Output, "pick an asset [1-%d, empty to quit]: ", len(resolution.Candidates))
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" || answer == "q" {
+			return nil, resolution.Err
+		}
+
+		n, perr := strconv.Atoi(answer)
+		if perr == nil && 0 < n && n <= len(resolution.Candidates) {
+			return resolution.Candidates[n-1].Asset, nil
+		}
+		if err != nil {
+			return nil, resolution.Err
+		}
+		color.Magenta("invalid number: %s", answer)
+	}
+}
+
+// replaceAsset returns the asset keyword flag of the asset if it selects the asset,
+// otherwise the glob flag of the asset name.
+func replaceAsset(release *github.RepositoryRelease, opt *github.AssetOptions, asset *github.ReleaseAsset) *assetReplace {
+	replace := &assetReplace{name: opt.Name}
+	switch {
+	case opt.NameTemplate != "":
+		replace.flags = []string{"--asset-template"}
+	case opt.NameRegex != "" || opt.NameGlob != "":
+		replace.flags = []string{"--asset-regex", "--asset-glob"}
+	default:
+		keyword := *opt
+		keyword.Name = asset.GetName()
+		resolution, err := github.ResolveAssets(release, &keyword)
+		if err == nil && resolution.Err == nil && resolution.Assets[0] == asset {
+			replace.args = []string{"--asset", asset.GetName()}
+			return replace
+		}
+	}
+
+	replace.args = []string{"--asset-glob", globEscape(asset.GetName())}
+	return replace
+}
+
+// commandLine returns the command line of the arguments with the replaced asset flags.
+func commandLine(args []string, replaces []*assetReplace) string {
+	names, flags := map[string]bool{}, map[string]bool{}
+	for _, v := range replaces {
+		if v.name != "" {
+			names[v.name] = true
+		}
+		for _, flag := range v.flags {
+			flags[flag] = true
+		}
+	}
+
+	line := []string{filepath.Base(args[0])}
+	for i := 1; i < len(args); i++ {
+		flag, value := args[i], ""
+		inline := strings.HasPrefix(flag, "--") && strings.Contains(flag, "=")
+		if inline {
+			kv := strings.SplitN(flag, "=", 2)
+			flag, value = kv[0], kv[1]
+		} else if i+1 < len(args) {
+			value = args[i+1]
+		}
+
+		replaced := (flag == "--asset" && names[value]) || flags[flag]
+		switch {
+		case replaced && !inline:
+			i++
+		case replaced:
+		default:
+			line = append(line, shellQuote(args[i]))
+		}
+	}
+
+	for _, v := range replaces {
+		for _, arg := range v.args {
+			line = append(line, shellQuote(arg))
+		}
+	}
+	return strings.Join(line, " ")
+}
+
+var globSpecial = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+func globEscape(name string) string {
+	return globSpecial.Replace(name)
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+,-]+$`)
+
+// shellQuote quotes the argument for a POSIX shell if it has special characters.
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,178 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/iwaltgen/github-dl/pkg/github"
+)
+
+// resolveCmd represents the resolve command
+var resolveCmd = &cobra.Command{
+	Use:   "resolve",
+	Short: "Show the asset selection of a release without downloading.",
+	Long: `Show the asset selection of a release without downloading.
+Every asset is listed with the score and result of each selection criterion,
+and the selected assets are marked with "*".
+
+Example:
+github-dl --repo cli/cli resolve --asset gh
+github-dl --repo BurntSushi/ripgrep resolve --asset ripgrep --os linux --libc musl
+github-dl --repo goreleaser/goreleaser resolve --asset-glob "*.sbom" --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signalContext()
+		defer cancel()
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		opts, err := makeAssetOptions()
+		if err != nil {
+			color.Magenta(err.Error())
+			fmt.Println(cmd.UsageString())
+			os.Exit(1)
+		}
+
+		return showResolution(ctx, client, opts)
+	},
+}
+
+var jsonOutput bool
+
+type resolveResult struct {
+	Repository string               `json:"repository"`
+	Tag        string               `json:"tag"`
+	URL        string               `json:"url,omitempty"`
+	Assets     []*github.Resolution `json:"assets"`
+}
+
+// showResolution prints the asset selection of the options,
+// and returns the first selection error.
+func showResolution(ctx context.Context, client *github.Client, opts []*github.AssetOptions) error {
+	release, resolutions, err := client.ResolveReleaseAssets(ctx, github.Repository(repo), opts)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		err = printPrettyJSON(Cyan, &resolveResult{
+			Repository: repo,
+			Tag:        release.GetTagName(),
+			URL:        release.GetHTMLURL(),
+			Assets:     resolutions,
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		color.Cyan("repository:\t%s", repo)
+		color.Cyan("release:\t%s (%s)", release.GetTagName(), release.GetHTMLURL())
+		for _, v := range resolutions {
+			showCandidates(v)
+		}
+	}
+
+	for _, v := range resolutions {
+		if v.Err != nil {
+			return v.Err
+		}
+	}
+	return nil
+}
+
+func showCandidates(resolution *github.Resolution) {
+	var keywords []string
+	for _, kv := range [][2]string{
+		{"name", resolution.Name},
+		{"regex", resolution.Regex},
+		{"glob", resolution.Glob},
+		{"template", resolution.Template},
+		{"exclude", strings.Join(resolution.Exclude, " ")},
+		{"os", resolution.OS},
+		{"arch", resolution.Arch},
+		{"libc", resolution.Libc},
+	} {
+		if kv[1] != "" {
+			keywords = append(keywords, kv[0]+": "+kv[1])
+		}
+	}
+	fmt.Println()
+	color.Cyan("asset keyword:\t[%s]", strings.Join(keywords, ", "))
+
+	selected := map[string]bool{}
+	for _, v := range resolution.Selected {
+		selected[v] = true
+	}
+
+	for _, v := range resolution.Candidates {
+		line := fmt.Sprintf("%-50s %4d  %s", v.Name, v.Score, formatCriteria(v.Criteria))
+		switch {
+		case selected[v.Name]:
+			color.Green("* " + line)
+		case v.Rejected:
+			color.Yellow("  " + line)
+		default:
+			color.White("  " + line)
+		}
+	}
+
+	if resolution.Err != nil {
+		color.Magenta("error:\t\t%s", resolution.Error)
+		return
+	}
+	color.Green("selected:\t%s", strings.Join(resolution.Selected, ", "))
+}
+
+// formatCriteria formats criteria which affect the selection. (e.g. os=linux(+8) !arch=arm64)
+func formatCriteria(criteria []*github.Criterion) string {
+	var parts []string
+	for _, v := range criteria {
+		if !v.Matched && !v.Reject && v.Score == 0 {
+			continue
+		}
+
+		part := v.Name
+		if v.Detail != "" {
+			part += "=" + v.Detail
+		}
+		switch {
+		case v.Reject:
+			part = "!" + part
+		case v.Score != 0:
+			part += fmt.Sprintf("(%+d)", v.Score)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+
+	flagSet := resolveCmd.Flags()
+	addAssetFlags(flagSet)
+	flagSet.BoolVar(&jsonOutput, "json", jsonOutput, "print the asset selection as JSON")
+}
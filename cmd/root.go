@@ -28,15 +28,21 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cheggaaa/pb/v3"
 	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
 	"github.com/reactivex/rxgo/v2"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/iwaltgen/github-dl/pkg/cache"
 	"github.com/iwaltgen/github-dl/pkg/github"
+	"github.com/iwaltgen/github-dl/pkg/install"
+	"github.com/iwaltgen/github-dl/pkg/platform"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -53,35 +59,90 @@ Example:
 github-dl --repo cli/cli --asset gh --dest bin --pick gh
 github-dl --repo golangci/golangci-lint --asset golangci-lint --pick golangci-lint
 github-dl --repo uber/prototool --asset prototool --target prototool
-github-dl --repo google/protobuf --asset protoc --target protoc --pick protoc`,
+github-dl --repo google/protobuf --asset protoc --target protoc --pick protoc
+github-dl --repo protocolbuffers/protobuf --asset protoc --pick protoc=bin/protoc --pick include=include
+github-dl --repo cli/cli --asset gh --pick gh --pick "*.1=man/" --preserve-paths
+github-dl --repo goreleaser/goreleaser --asset goreleaser --asset checksums --pick goreleaser
+github-dl --repo cli/cli --source tarball --ref v1.0.0 --strip-components 1 --dest vendor/cli
+github-dl --repo cli/cli --asset-regex '^gh_.*_linux_amd64\.tar\.gz$' --pick gh
+github-dl --repo goreleaser/goreleaser --asset-glob "*.sbom" --dest sbom
+github-dl --repo cli/cli --asset-template 'gh_{{.Version}}_{{map .OS "darwin:macOS"}}_{{.Arch}}{{.Ext}}' --pick gh
+github-dl --repo cli/cli --asset gh --exclude "*.deb" --exclude "re:\.(rpm|sha256)$"
+github-dl --repo cli/cli --asset gh --os linux --dest - | tar -xz
+github-dl --repo cli/cli --asset gh --pick gh --dest - | install -m755 /dev/stdin bin/gh
+github-dl --repo cli/cli --asset gh --dry-run`,
 		version,
 		commitHash,
 		lastModified().Format(time.RFC3339),
 	),
 	SilenceErrors: true,
 	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+		conf, err = loadConfig(configPath, cmd.Flags().Changed("config"))
+		return err
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ctx := context.Background()
-		client := github.NewClient(githubToken(), verbose)
+		ctx, cancel := signalContext()
+		defer cancel()
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
 
-		opt, err := makeAssetOptions()
+		opts, err := makeAssetOptions()
 		if err != nil {
 			color.Magenta(err.Error())
 			fmt.Println(cmd.UsageString())
 			os.Exit(1)
 		}
 
+		// keep the standard output clean for the asset stream.
+		if dest == github.StdoutPath {
+			color.Output = colorable.NewColorableStderr()
+		}
+
+		if dryRun {
+			if source != "" {
+				return errors.New("conflict flags: --source, --dry-run")
+			}
+			return showResolution(ctx, client, opts)
+		}
+
 		if verbose {
 			color.Cyan("repository:\t%s", repo)
 			color.Cyan("release tag:\t%s", tag)
 		}
 
-		asset, observable, err := client.DownloadReleaseAsset(ctx, github.Repository(repo), opt)
+		// a failed download cancels the others.
+		dlCtx, dlCancel := context.WithCancel(ctx)
+		defer dlCancel()
+
+		if source != "" {
+			asset, observable, err := client.DownloadSourceArchive(dlCtx,
+				github.Repository(repo), github.ArchiveFormat(source), ref, opts[0])
+			if err != nil {
+				return err
+			}
+			return showDownloadProgress(ctx, dlCancel, []*github.ReleaseAsset{asset}, observable)
+		}
+
+		release, resolutions, err := client.ResolveReleaseAssets(dlCtx, github.Repository(repo), opts)
+		if err != nil {
+			return err
+		}
+		if stdinTerminal() {
+			if err := pickAssets(release, resolutions, opts); err != nil {
+				return err
+			}
+		}
+
+		assets, observable, err := client.DownloadResolvedAssets(dlCtx, github.Repository(repo), release, resolutions)
 		if err != nil {
 			return err
 		}
 
-		return showDownloadProgress(ctx, asset, observable)
+		return showDownloadProgress(ctx, dlCancel, assets, observable)
 	},
 }
 
@@ -89,113 +150,416 @@ var (
 	verbose  bool
 	tokenEnv string
 	token    string
+	host     string
 	repo     string
 )
 
 var (
-	asset     string
-	tag       = "latest"
-	osname    = runtime.GOOS
-	osAlias   = "darwin:macos,osx;windows:win"
-	arch      = runtime.GOARCH
-	archAlias = "amd64:x86_64"
-	dest, _   = os.Getwd()
-	target    string
-	pick      string
+	cacheDir     = defaultCacheDir()
+	cacheMaxSize = "1G"
+	noCache      bool
+)
+
+var httpOpt = github.HTTPOptions{
+	ConnectTimeout: 30 * time.Second,
+	IdleTimeout:    90 * time.Second,
+}
+
+var (
+	assets        []string
+	assetRegex    string
+	assetGlob     string
+	excludes      []string
+	assetTemplate string
+	tag           = "latest"
+	osname        = runtime.GOOS
+	osAlias       string
+	arch          = runtime.GOARCH
+	archAlias     string
+	libc          string
+	dest, _       = os.Getwd()
+	target        string
+	picks         []string
+	preserve      bool
+	strip         int
+	source        string
+	ref           string
+	limitRate     string
+	force         bool
+	replaceDirs   bool
+	noClobber     bool
+	backup        string
+	mode          string
+	dryRun        bool
+	concurrency   = github.DefaultConcurrency
 )
 
 func init() {
 	pflagSet := rootCmd.PersistentFlags()
 	pflagSet.BoolVarP(&verbose, "verbose", "v", verbose, "verbose output")
-	pflagSet.StringVar(&tokenEnv, "token-env", "GITHUB_TOKEN", "github oauth2 token environment name")
+	pflagSet.StringVar(&configPath, "config", defaultConfigPath(), "config file path")
+	pflagSet.StringVar(&tokenEnv, "token-env", tokenEnv,
+		"github oauth2 token environment name (default: GITHUB_TOKEN, GITHUB_ENTERPRISE_TOKEN for enterprise hosts)")
 	pflagSet.StringVar(&token, "token", token, "github oauth2 token value (optional)")
+	pflagSet.StringVar(&host, "host", host, "github enterprise server host or url (default: GH_HOST environment, config or github.com)")
 	pflagSet.StringVar(&repo, "repo", repo, "github repository (owner/name)")
+	pflagSet.StringVar(&cacheDir, "cache-dir", cacheDir, "download cache folder (env: GITHUB_DL_CACHE_DIR)")
+	pflagSet.StringVar(&cacheMaxSize, "cache-max-size", cacheMaxSize, "download cache size cap, e.g. 500M, 2G (0 is unlimited)")
+	pflagSet.StringVar(&httpOpt.CAFile, "cacert", httpOpt.CAFile, "trust certificates of the PEM bundle file (optional)")
+	pflagSet.StringVar(&httpOpt.ProxyURL, "proxy", httpOpt.ProxyURL, "proxy server url (default: environment settings)")
+	pflagSet.BoolVar(&httpOpt.InsecureSkipVerify, "insecure", httpOpt.InsecureSkipVerify, "skip TLS certificate verification (lab use only)")
+	pflagSet.DurationVar(&httpOpt.ConnectTimeout, "connect-timeout", httpOpt.ConnectTimeout, "maximum time to connect")
+	pflagSet.DurationVar(&httpOpt.IdleTimeout, "idle-timeout", httpOpt.IdleTimeout, "maximum time of an idle connection")
+	pflagSet.DurationVar(&httpOpt.Timeout, "timeout", httpOpt.Timeout, "maximum time of a request (0 is unlimited)")
+	pflagSet.DurationVar(&httpOpt.StallTimeout, "stall-timeout", httpOpt.StallTimeout, "abort a download without data for the duration (0 is disabled)")
+	pflagSet.IntVar(&concurrency, "concurrency", concurrency, "maximum number of concurrent downloads (0 is unlimited)")
 
 	flagSet := rootCmd.Flags()
-	flagSet.StringVar(&asset, "asset", asset, "asset name keyword")
+	addAssetFlags(flagSet)
+	flagSet.BoolVar(&dryRun, "dry-run", dryRun, "show the asset selection like the resolve command, without downloading")
+	flagSet.BoolVar(&jsonOutput, "json", jsonOutput, "print the asset selection of --dry-run as JSON")
+	flagSet.StringVar(&source, "source", source, "download the repository source archive instead of assets: tarball, zipball (optional)")
+	flagSet.StringVar(&ref, "ref", ref, "tag, branch or commit of the source archive (default: the release tag)")
+	flagSet.BoolVar(&noCache, "no-cache", noCache, "download without the cache")
+	flagSet.BoolVar(&force, "force", force, "download and install even if the install receipt matches the release")
+	addInstallFlags(flagSet)
+	addArchiveFlags(flagSet)
+}
+
+// addAssetFlags adds flags of the release asset selection to the command.
+func addAssetFlags(flagSet *pflag.FlagSet) {
+	flagSet.StringArrayVar(&assets, "asset", assets, "asset name keyword, repeatable to download several assets of the release")
+	flagSet.StringVar(&assetRegex, "asset-regex", assetRegex,
+		"download every asset matched the regular expression of the name, instead of keywords (optional)")
+	flagSet.StringVar(&assetGlob, "asset-glob", assetGlob,
+		"download every asset matched the glob pattern of the name, instead of keywords (optional)")
+	flagSet.StringVar(&assetTemplate, "asset-template", assetTemplate,
+		"download the asset of the go template name with .Version, .Tag, .OS, .Arch, .Ext, instead of keywords (optional)")
+	flagSet.StringArrayVar(&excludes, "exclude", excludes,
+		"skip assets matched the glob pattern, or the regular expression with re: prefix, repeatable (optional)")
 	flagSet.StringVar(&tag, "tag", tag, "release tag")
 	flagSet.StringVar(&osname, "os", osname, "os keyword")
-	flagSet.StringVar(&osAlias, "os-alias", osAlias, "os keyword alias")
 	flagSet.StringVar(&arch, "arch", arch, "arch keyword")
-	flagSet.StringVar(&archAlias, "arch-alias", archAlias, "arch keyword alias")
-	flagSet.StringVar(&dest, "dest", dest, "destination path")
+	addAliasFlags(flagSet)
+	flagSet.StringVar(&libc, "libc", libc, "preferred C library of assets: gnu, musl, msvc (default: detect the host)")
+}
+
+// addArchiveFlags adds flags of the archive extraction to the download command.
+func addArchiveFlags(flagSet *pflag.FlagSet) {
+	flagSet.StringArrayVar(&picks, "pick", picks,
+		"extract archive and pick a file name pattern, repeatable with an optional destination: pattern=path (optional)")
+	flagSet.BoolVar(&preserve, "preserve-paths", preserve, "keep the archive folder structure of picked files")
+	flagSet.IntVar(&strip, "strip-components", strip, "remove the number of leading folders from extracted file paths")
+}
+
+// addInstallFlags adds flags of the destination files to the download command.
+func addInstallFlags(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&dest, "dest", dest, "destination path (\"-\" writes to stdout)")
 	flagSet.StringVar(&target, "target", target, "rename destination file (optional)")
-	flagSet.StringVar(&pick, "pick", pick, "extract archive and pick a file name pattern (optional)")
+	flagSet.StringVar(&limitRate, "limit-rate", limitRate, "maximum download speed per second, e.g. 500K, 5M (optional)")
+	flagSet.StringVar(&mode, "mode", mode, "octal file mode of the raw asset or picked files, e.g. 0755 (default: detect executables)")
+	flagSet.BoolVar(&noClobber, "no-clobber", noClobber, "keep existing destination files")
+	flagSet.BoolVar(&replaceDirs, "replace-folders", replaceDirs, "replace existing folders in the way of destination files")
+	flagSet.StringVar(&backup, "backup", backup, "keep overwritten files as backups: simple (name.bak), numbered (name.bak.N)")
+	flagSet.Lookup("backup").NoOptDefVal = "simple"
 }
 
-func githubToken() string {
-	if token != "" {
-		return token
+func newClient() (*github.Client, error) {
+	opts := []github.Option{github.WithHTTPOptions(&httpOpt), github.WithConcurrency(concurrency)}
+	if baseURL := enterpriseURL(githubHost()); baseURL != "" {
+		opts = append(opts, github.WithBaseURL(baseURL))
+	}
+
+	if !noCache && cacheDir != "" {
+		c, err := newCache()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, github.WithCache(c))
 	}
-	return os.Getenv(tokenEnv)
+	return github.NewClientWithOptions(githubToken(), verbose, opts...)
 }
 
-func makeAssetOptions() (*github.AssetOptions, error) {
-	if asset == "" {
-		return nil, errors.New("require asset name: see flags --asset")
+func newCache() (*cache.Cache, error) {
+	maxSize, err := parseByteSize(cacheMaxSize)
+	if err != nil {
+		return nil, errors.New("parse size error: see flags --cache-max-size")
+	}
+	return cache.New(cacheDir, maxSize), nil
+}
+
+func defaultCacheDir() string {
+	if v := os.Getenv("GITHUB_DL_CACHE_DIR"); v != "" {
+		return v
+	}
+
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+func makeAssetOptions() ([]*github.AssetOptions, error) {
+	byPattern := assetRegex != "" || assetGlob != ""
+	names := assets
+	switch {
+	case source != "" && (len(assets) > 0 || byPattern || assetTemplate != ""):
+		return nil, errors.New("conflict flags: --source, --asset")
+	case source != "" && source != string(github.Tarball) && source != string(github.Zipball):
+		return nil, errors.New("unknown source archive: see flags --source")
+	case source != "":
+		names = []string{""}
+	case ref != "":
+		return nil, errors.New("require source archive: see flags --source")
+	case len(assets) == 0 && !byPattern && assetTemplate == "":
+		return nil, errors.New("require asset name: see flags --asset, --asset-regex, --asset-glob, --asset-template")
+	}
+
+	for _, v := range assets {
+		if v == "" {
+			return nil, errors.New("require asset name: see flags --asset")
+		}
+	}
+	if len(assets) > 1 && (dest == github.StdoutPath || target != "") {
+		return nil, errors.New("several assets can not use --target or stdout: see flags --asset")
 	}
 
-	osAliasMap, err := parseAlias(osAlias)
+	osTable, archTable, err := aliasTables()
 	if err != nil {
-		return nil, errors.New("parse alias error: see flags --os-alias")
+		return nil, err
 	}
+	archName, _ := platform.Arch(arch)
 
-	archAliasMap, err := parseAlias(archAlias)
+	assetLibc, err := hostLibc()
 	if err != nil {
-		return nil, errors.New("parse alias error: see flags --arch-alias")
+		return nil, err
+	}
+
+	base, err := makeInstallOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []*github.AssetOptions
+	if byPattern {
+		opt := *base
+		opt.Tag = tag
+		opt.NameRegex = assetRegex
+		opt.NameGlob = assetGlob
+		opt.Exclude = excludes
+		opts = append(opts, &opt)
+	}
+	if assetTemplate != "" {
+		opt := *base
+		opt.Tag = tag
+		opt.NameTemplate = assetTemplate
+		opt.Exclude = excludes
+		opt.OS = osname
+		opt.Arch = arch
+		opts = append(opts, &opt)
+	}
+	for _, name := range names {
+		opt := *base
+		opt.Name = name
+		opt.Exclude = excludes
+		opt.Tag = tag
+		opt.OS = osname
+		opt.OSAlias = osTable[platform.OS(osname)]
+		opt.Arch = arch
+		opt.ArchAlias = archTable[archName]
+		opt.Libc = assetLibc
+		opts = append(opts, &opt)
+	}
+	return opts, nil
+}
+
+// hostLibc returns the libc flag, or the detected libc of the host for the host os.
+func hostLibc() (string, error) {
+	if libc != "" {
+		v := platform.Libc(libc)
+		if v != "gnu" && v != "musl" && v != "msvc" {
+			return "", errors.New("unknown libc: see flags --libc")
+		}
+		return v, nil
+	}
+	if platform.OS(osname) != runtime.GOOS {
+		return "", nil
+	}
+	return platform.HostLibc(), nil
+}
+
+// makeInstallOptions makes options of the destination files.
+func makeInstallOptions() (*github.AssetOptions, error) {
+	if strip < 0 {
+		return nil, errors.New("negative number: see flags --strip-components")
+	}
+
+	limitRateBytes, err := parseByteSize(limitRate)
+	if err != nil {
+		return nil, errors.New("parse size error: see flags --limit-rate")
+	}
+
+	var fileMode os.FileMode
+	if mode != "" {
+		v, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || v > 0777 {
+			return nil, errors.New("parse mode error: see flags --mode")
+		}
+		fileMode = os.FileMode(v)
+	}
+
+	overwrite := install.Overwrite
+	switch {
+	case noClobber && backup != "":
+		return nil, errors.New("conflict flags: --no-clobber, --backup")
+	case noClobber && replaceDirs:
+		return nil, errors.New("conflict flags: --no-clobber, --replace-folders")
+	case noClobber:
+		overwrite = install.NoClobber
+	case backup == "simple":
+		overwrite = install.Backup
+	case backup == "numbered":
+		overwrite = install.NumberedBackup
+	case backup != "":
+		return nil, errors.New("unknown backup: see flags --backup")
 	}
 
 	return &github.AssetOptions{
-		Name:        asset,
-		Tag:         tag,
-		OS:          osname,
-		OSAlias:     osAliasMap[osname],
-		Arch:        arch,
-		ArchAlias:   archAliasMap[arch],
-		DestPath:    dest,
-		Target:      target,
-		PickPattern: pick,
+		DestPath:        dest,
+		Target:          target,
+		Picks:           parsePicks(picks),
+		PreservePaths:   preserve,
+		StripComponents: strip,
+		LimitRate:       limitRateBytes,
+		Mode:            fileMode,
+		Overwrite:       overwrite,
+		ReplaceFolders:  replaceDirs,
+		Force:           force,
 	}, nil
 }
 
+// parsePicks parses pick flags. (pattern or pattern=destination)
+func parsePicks(flagPicks []string) []github.Pick {
+	var ret []github.Pick
+	for _, v := range flagPicks {
+		kv := strings.SplitN(v, "=", 2)
+		pick := github.Pick{Pattern: kv[0]}
+		if len(kv) == 2 {
+			pick.Destination = kv[1]
+		}
+		ret = append(ret, pick)
+	}
+	return ret
+}
+
+// parseAlias parses alias flags. (e.g. darwin:macos,osx;windows:win)
+// empty entries are ignored, and keywords are lower case.
 func parseAlias(flagAlias string) (map[string][]string, error) {
 	ret := map[string][]string{}
-	aliases := strings.Split(flagAlias, ";")
-	for _, alias := range aliases {
+	for _, alias := range strings.Split(flagAlias, ";") {
+		if strings.TrimSpace(alias) == "" {
+			continue
+		}
+
 		kv := strings.Split(alias, ":")
-		if len(kv) != 2 {
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
 			return nil, fmt.Errorf("parse alias: %v", kv)
 		}
-		k, v := kv[0], strings.Split(kv[1], ",")
-		ret[k] = v
+		k := strings.ToLower(strings.TrimSpace(kv[0]))
+		for _, v := range strings.Split(kv[1], ",") {
+			if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+				ret[k] = append(ret[k], v)
+			}
+		}
 	}
 	return ret, nil
 }
 
 func showDownloadProgress(ctx context.Context,
-	asset *github.ReleaseAsset,
+	cancel context.CancelFunc,
+	assets []*github.ReleaseAsset,
 	observable rxgo.Observable,
 ) error {
-	totalSize := int64(asset.GetSize())
+	var totalSize int64
+	for _, asset := range assets {
+		totalSize += int64(asset.GetSize())
+	}
 	pbbar := pb.Full.New(int(totalSize))
 	pbbar.Set(pb.Bytes, true)
 	pbbar.Set(pb.Terminal, true)
 
 	if verbose {
-		color.Cyan("release asset:\t%s (%s)", asset.GetName(), pbbar.Format(totalSize))
+		for _, asset := range assets {
+			color.Cyan("release asset:\t%s (%s)", asset.GetName(), pbbar.Format(int64(asset.GetSize())))
+		}
 	}
 
-	pbbar.Start()
+	// drain the stream until it is closed, so the download can clean up
+	// temporary files before the process exits.
+	var err error
+	var results []*github.InstallResult
+	received := map[*github.ReleaseAsset]int64{}
+	totals := map[*github.ReleaseAsset]int64{}
 	for item := range observable.Observe(rxgo.WithContext(ctx)) {
 		if item.Error() {
-			return item.E
+			if err == nil {
+				err = item.E
+				cancel()
+			}
+			continue
 		}
 
-		progress := item.V.(*github.DownloadProgress)
-		pbbar.SetCurrent(progress.Received)
+		switch v := item.V.(type) {
+		case *github.DownloadProgress:
+			if !pbbar.IsStarted() {
+				pbbar.Start()
+			}
+			received[v.Asset] = v.Received
+			totals[v.Asset] = v.Total
+
+			// the size of a source archive is known on the download.
+			var current, total int64
+			for asset, n := range received {
+				current += n
+				total += totals[asset]
+			}
+			if total > totalSize {
+				totalSize = total
+				pbbar.SetTotal(totalSize)
+			}
+			pbbar.SetCurrent(current)
+
+		case *github.InstallResult:
+			results = append(results, v)
+		}
+	}
+	if pbbar.IsStarted() {
+		if err == nil && totalSize > 0 {
+			pbbar.SetCurrent(totalSize)
+		}
+		pbbar.Finish()
+	}
+	if err != nil {
+		return err
 	}
-	pbbar.SetCurrent(totalSize)
-	pbbar.Finish()
 
+	for _, result := range results {
+		showInstallResult(result)
+	}
 	return nil
 }
+
+func showInstallResult(result *github.InstallResult) {
+	if result.Skipped {
+		color.Green("already installed:\t%s (%s)", result.Asset.GetName(), strings.Join(result.Files, ", "))
+	} else if verbose {
+		color.Cyan("installed files:\t%s", strings.Join(result.Files, "\n\t\t\t"))
+	}
+	if len(result.Kept) > 0 {
+		color.Yellow("kept files:\t%s", strings.Join(result.Kept, "\n\t\t"))
+	}
+	if len(result.Backups) > 0 && verbose {
+		color.Cyan("backup files:\t%s", strings.Join(result.Backups, "\n\t\t"))
+	}
+}
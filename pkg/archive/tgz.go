@@ -19,6 +19,7 @@ package archive
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -28,23 +29,31 @@ import (
 // TarGz unarchives tar.gz(tgz) archive file.
 type TarGz struct{}
 
-// Unarchive unpacks the .zip file at source to destination.
-func (t TarGz) Unarchive(source, destination string) error {
+// Unarchive unpacks the .tar.gz file at source to destination.
+// It returns paths of the written files.
+func (t TarGz) Unarchive(ctx context.Context, source, destination string) (files []string, err error) {
 	sf, err := os.Open(source)
 	if err != nil {
-		return fmt.Errorf("open source file error: %w", err)
+		return nil, fmt.Errorf("open source file error: %w", err)
 	}
 	defer sf.Close()
 
 	gr, err := gzip.NewReader(sf)
 	if err != nil {
-		return fmt.Errorf("open gzip reader error: %w", err)
+		return nil, fmt.Errorf("open gzip reader error: %w", err)
 	}
 	defer gr.Close()
 
+	ex := newExtractor(ctx)
+	defer func() {
+		if err != nil {
+			ex.rollback()
+		}
+	}()
+
 	tr := tar.NewReader(gr)
-	if err := mkdir(destination, os.ModePerm); err != nil {
-		return err
+	if err := ex.mkdir(destination, os.ModePerm); err != nil {
+		return nil, err
 	}
 
 	for {
@@ -53,7 +62,7 @@ func (t TarGz) Unarchive(source, destination string) error {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("reader next error: %w", err)
+			return nil, fmt.Errorf("reader next error: %w", err)
 		}
 
 		fpath := filepath.Join(destination, header.Name)
@@ -61,20 +70,20 @@ func (t TarGz) Unarchive(source, destination string) error {
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			if err := mkdir(fpath, fmode); err != nil {
-				return err
+			if err := ex.mkdir(fpath, fmode); err != nil {
+				return nil, err
 			}
 
 		case tar.TypeReg, tar.TypeRegA, tar.TypeChar, tar.TypeBlock, tar.TypeFifo, tar.TypeGNUSparse:
-			if err := writeNewFile(fpath, tr, fmode); err != nil {
-				return err
+			if err := ex.writeNewFile(fpath, tr, fmode); err != nil {
+				return nil, err
 			}
 
 		case tar.TypeXGlobalHeader, tar.TypeSymlink, tar.TypeLink: // ignore
 
 		default:
-			return fmt.Errorf("unknown type error: %v", header.Typeflag)
+			return nil, fmt.Errorf("unknown type error: %v", header.Typeflag)
 		}
 	}
-	return nil
+	return ex.files, nil
 }
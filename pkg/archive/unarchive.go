@@ -17,6 +17,7 @@ limitations under the License.
 package archive
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -32,21 +33,24 @@ var (
 
 // Unarchiver is a type that can extract archive files into a folder.
 type Unarchiver interface {
-	Unarchive(source, destination string) error
+	Unarchive(ctx context.Context, source, destination string) ([]string, error)
 }
 
 // Unarchive unarchives the given archive file into the destination folder.
 // The archive format is selected implicitly.
-func Unarchive(source, destination string) error {
+// It returns paths of the written files.
+// Files and folders created by a failed or canceled unarchive are removed.
+func Unarchive(ctx context.Context, source, destination string) ([]string, error) {
 	unarchiver, err := byExtension(source)
 	if err != nil {
-		return fmt.Errorf("unarchive `%s` error: %w", source, err)
+		return nil, fmt.Errorf("unarchive `%s` error: %w", source, err)
 	}
 
-	if err := unarchiver.Unarchive(source, destination); err != nil {
-		return fmt.Errorf("unarchive `%s` error: %w", source, err)
+	files, err := unarchiver.Unarchive(ctx, source, destination)
+	if err != nil {
+		return nil, fmt.Errorf("unarchive `%s` error: %w", source, err)
 	}
-	return nil
+	return files, nil
 }
 
 // Support check for handle the archive file format.
@@ -69,7 +73,48 @@ func byExtension(fpath string) (Unarchiver, error) {
 	}
 }
 
-func mkdir(dpath string, mode os.FileMode) error {
+// extractor writes archive entries and remembers what it created,
+// so a failed extraction can be rolled back.
+type extractor struct {
+	ctx     context.Context
+	created []string
+	files   []string
+}
+
+func newExtractor(ctx context.Context) *extractor {
+	return &extractor{ctx: ctx}
+}
+
+// rollback removes created files and folders in reverse order.
+func (e *extractor) rollback() {
+	for i := len(e.created) - 1; i >= 0; i-- {
+		_ = os.RemoveAll(e.created[i])
+	}
+}
+
+// track remembers the top most missing path of fpath before it is created.
+func (e *extractor) track(fpath string) {
+	missing := ""
+	for p := filepath.Clean(fpath); ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil {
+			break
+		}
+		missing = p
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+	if missing != "" {
+		e.created = append(e.created, missing)
+	}
+}
+
+func (e *extractor) mkdir(dpath string, mode os.FileMode) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+
+	e.track(dpath)
 	err := os.MkdirAll(dpath, mode)
 	if err != nil {
 		return fmt.Errorf("mkdir `%s` error: %w", dpath, err)
@@ -77,7 +122,12 @@ func mkdir(dpath string, mode os.FileMode) error {
 	return nil
 }
 
-func writeNewFile(fpath string, in io.Reader, mode os.FileMode) error {
+func (e *extractor) writeNewFile(fpath string, in io.Reader, mode os.FileMode) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+
+	e.track(fpath)
 	err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm)
 	if err != nil {
 		return fmt.Errorf("mkdir `%s` for file error: %w", fpath, err)
@@ -89,9 +139,28 @@ func writeNewFile(fpath string, in io.Reader, mode os.FileMode) error {
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, in)
+	_, err = io.Copy(out, NewContextReader(e.ctx, in))
 	if err != nil {
 		return fmt.Errorf("write file `%s`: %w", fpath, err)
 	}
+	e.files = append(e.files, fpath)
 	return nil
 }
+
+// contextReader is an io.Reader that stops reading when the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// NewContextReader returns an io.Reader that stops reading when the context is done.
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -18,6 +18,7 @@ package archive
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -27,36 +28,45 @@ import (
 type Zip struct{}
 
 // Unarchive unpacks the .zip file at source to destination.
-func (z Zip) Unarchive(source, destination string) error {
+// It returns paths of the written files.
+func (z Zip) Unarchive(ctx context.Context, source, destination string) (files []string, err error) {
 	r, err := zip.OpenReader(source)
 	if err != nil {
-		return fmt.Errorf("open reader error: %w", err)
+		return nil, fmt.Errorf("open reader error: %w", err)
 	}
 	defer r.Close()
 
-	if err := mkdir(destination, os.ModePerm); err != nil {
-		return err
+	ex := newExtractor(ctx)
+	defer func() {
+		if err != nil {
+			ex.rollback()
+		}
+	}()
+
+	if err := ex.mkdir(destination, os.ModePerm); err != nil {
+		return nil, err
 	}
 
 	for _, zf := range r.File {
-		f, err := zf.Open()
-		if err != nil {
-			return fmt.Errorf("open file `%s` error: %w", zf.Name, err)
-		}
-		defer f.Close()
-
-		fileinfo := zf.FileInfo()
-		fpath := filepath.Join(destination, zf.Name)
-		if fileinfo.IsDir() {
-			if err := mkdir(fpath, fileinfo.Mode()); err != nil {
-				return err
-			}
-			continue
+		if err := z.extract(ex, zf, destination); err != nil {
+			return nil, err
 		}
+	}
+	return ex.files, nil
+}
 
-		if err := writeNewFile(fpath, f, fileinfo.Mode()); err != nil {
-			return err
-		}
+func (z Zip) extract(ex *extractor, zf *zip.File, destination string) error {
+	fileinfo := zf.FileInfo()
+	fpath := filepath.Join(destination, zf.Name)
+	if fileinfo.IsDir() {
+		return ex.mkdir(fpath, fileinfo.Mode())
+	}
+
+	f, err := zf.Open()
+	if err != nil {
+		return fmt.Errorf("open file `%s` error: %w", zf.Name, err)
 	}
-	return nil
+	defer f.Close()
+
+	return ex.writeNewFile(fpath, f, fileinfo.Mode())
 }
@@ -0,0 +1,295 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the cache has no entry of the key.
+	ErrNotFound = errors.New("not found cache entry")
+)
+
+const (
+	blobsDir   = "blobs"
+	entriesDir = "entries"
+	entryExt   = ".json"
+
+	// blobGracePeriod keeps new blobs without an entry from Prune,
+	// because the entry may be written by another process.
+	blobGracePeriod = 10 * time.Minute
+)
+
+// DefaultDir returns the default cache folder. ($XDG_CACHE_HOME/github-dl)
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "github-dl"), nil
+}
+
+// Key identifies a cached release asset.
+// An updated asset has a new key, so it is downloaded again.
+type Key struct {
+	Host       string    `json:"host"`
+	Repository string    `json:"repository"`
+	AssetID    int64     `json:"asset_id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (k Key) id() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d/%d",
+		strings.ToLower(k.Host), strings.ToLower(k.Repository), k.AssetID, k.UpdatedAt.Unix())))
+	return hex.EncodeToString(sum[:])
+}
+
+// Entry is a cached release asset.
+type Entry struct {
+	Key
+	Name       string    `json:"name"`
+	Digest     string    `json:"digest"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+	AccessedAt time.Time `json:"accessed_at"`
+}
+
+// Cache is an on-disk content-addressed store of release assets.
+// Blobs are stored by the sha256 digest of the contents and
+// entries map the asset keys to the blobs.
+type Cache struct {
+	dir     string
+	maxSize int64
+}
+
+// New creates cache at the folder.
+// Least recently used entries are pruned over the max size after a put. (0 is unlimited)
+func New(dir string, maxSize int64) *Cache {
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+	}
+}
+
+// Dir returns the cache folder.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the entry and the blob file path of the key.
+func (c *Cache) Get(key Key) (*Entry, string, error) {
+	entry, err := c.readEntry(c.entryPath(key.id()))
+	if os.IsNotExist(err) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	blob := c.blobPath(entry.Digest)
+	if _, err := os.Stat(blob); err != nil {
+		_ = os.Remove(c.entryPath(key.id()))
+		return nil, "", ErrNotFound
+	}
+
+	entry.AccessedAt = time.Now()
+	if err := c.writeEntry(entry); err != nil {
+		return nil, "", err
+	}
+	return entry, blob, nil
+}
+
+// Create returns a writer which stores the written contents as the key.
+func (c *Cache) Create(key Key, name string) (*Writer, error) {
+	return newWriter(c, key, name)
+}
+
+// List returns the entries ordered by last access time.
+func (c *Cache) List() ([]*Entry, error) {
+	files, err := ioutil.ReadDir(filepath.Join(c.dir, entriesDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, file := range files {
+		if filepath.Ext(file.Name()) != entryExt {
+			continue
+		}
+
+		entry, err := c.readEntry(filepath.Join(c.dir, entriesDir, file.Name()))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].AccessedAt.After(entries[j].AccessedAt)
+	})
+	return entries, nil
+}
+
+// Size returns the total size of the blobs.
+func (c *Cache) Size() (int64, error) {
+	var size int64
+	err := filepath.Walk(filepath.Join(c.dir, blobsDir), func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return size, err
+}
+
+// Prune removes least recently used entries until the total size is under the max size,
+// and blobs which are not referenced. Recent blobs without an entry are kept.
+// It returns the removed entries.
+func (c *Cache) Prune(maxSize int64) ([]*Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	var removed []*Entry
+	blobs := map[string]bool{}
+	for _, entry := range entries {
+		if blobs[entry.Digest] {
+			continue
+		}
+		if maxSize > 0 && total+entry.Size > maxSize {
+			continue
+		}
+		blobs[entry.Digest] = true
+		total += entry.Size
+	}
+
+	referenced := map[string]bool{}
+	for _, entry := range entries {
+		if blobs[entry.Digest] {
+			continue
+		}
+		referenced[entry.Digest] = true
+		if err := os.Remove(c.entryPath(entry.id())); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, entry)
+	}
+
+	dir := filepath.Join(c.dir, blobsDir, "sha256")
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return removed, nil
+	}
+	if err != nil {
+		return removed, err
+	}
+
+	for _, file := range files {
+		if blobs[file.Name()] || time.Since(file.ModTime()) < blobGracePeriod && !referenced[file.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, file.Name())); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// Clear removes all entries and blobs.
+func (c *Cache) Clear() error {
+	for _, dir := range []string{entriesDir, blobsDir} {
+		if err := os.RemoveAll(filepath.Join(c.dir, dir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) entryPath(id string) string {
+	return filepath.Join(c.dir, entriesDir, id+entryExt)
+}
+
+func (c *Cache) blobPath(digest string) string {
+	return filepath.Join(c.dir, blobsDir, "sha256", digest)
+}
+
+func (c *Cache) readEntry(fpath string) (*Entry, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("parse cache entry `%s` error: %w", fpath, err)
+	}
+	return entry, nil
+}
+
+func (c *Cache) writeEntry(entry *Entry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.entryPath(entry.id()), data)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to the path.
+func writeFileAtomic(fpath string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(fpath), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), fpath)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "github-dl-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	return New(dir, 0)
+}
+
+func TestWriterCommit(t *testing.T) {
+	c := newTestCache(t)
+	key := Key{Host: "github.com", Repository: "o/r", AssetID: 1}
+
+	w, err := c.Create(key, "asset.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(w, bytes.NewBufferString("contents")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, blob, err := c.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "contents" || entry.Size != int64(len(data)) {
+		t.Errorf("cached %q (%d bytes)", data, entry.Size)
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	c := newTestCache(t)
+	key := Key{Host: "github.com", Repository: "o/r", AssetID: 1}
+
+	w, err := c.Create(key, "asset.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a closed file fails every write.
+	_ = w.file.Close()
+
+	for i := 0; i < 2; i++ {
+		n, err := w.Write([]byte("contents"))
+		if n != len("contents") || err != nil {
+			t.Fatalf("write = (%d, %v), want (%d, nil)", n, err, len("contents"))
+		}
+	}
+	if _, err := w.Commit(); err == nil {
+		t.Fatal("commit succeeded after a write error")
+	}
+	if _, _, err := c.Get(key); err != ErrNotFound {
+		t.Errorf("get = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestPruneRecentBlob(t *testing.T) {
+	c := newTestCache(t)
+
+	// blobs without an entry: one may be committing by another process.
+	recent := c.blobPath("recent")
+	old := c.blobPath("old")
+	for _, fpath := range []string{recent, old} {
+		if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fpath, []byte("contents"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := time.Now().Add(-2 * blobGracePeriod)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Prune(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("recent blob is pruned: %v", err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("old blob is not pruned: %v", err)
+	}
+}
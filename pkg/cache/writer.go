@@ -0,0 +1,135 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Writer writes contents into a temporary blob of the cache.
+// The contents are stored by Commit, or discarded by Abort.
+// A write error does not fail the writer of the contents. (e.g. a full disk)
+// The first error stops writing, and is returned by Commit.
+type Writer struct {
+	cache *Cache
+	key   Key
+	name  string
+	file  *os.File
+	hash  hash.Hash
+	size  int64
+	err   error
+}
+
+func newWriter(c *Cache, key Key, name string) (*Writer, error) {
+	dir := filepath.Join(c.dir, blobsDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	file, err := ioutil.TempFile(dir, ".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		cache: c,
+		key:   key,
+		name:  name,
+		file:  file,
+		hash:  sha256.New(),
+	}, nil
+}
+
+// Write writes the contents into the blob. It always returns len(p) and nil.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return len(p), nil
+	}
+
+	n, err := w.file.Write(p)
+	w.hash.Write(p[:n])
+	w.size += int64(n)
+	if err != nil {
+		w.err = fmt.Errorf("write cache blob error: %w", err)
+	}
+	return len(p), nil
+}
+
+// Size returns the written bytes.
+func (w *Writer) Size() int64 {
+	return w.size
+}
+
+// Commit stores the written contents and the entry of the key.
+// It returns the first write error without storing the contents.
+func (w *Writer) Commit() (*Entry, error) {
+	defer w.Abort()
+
+	if w.err != nil {
+		return nil, w.err
+	}
+	if err := w.file.Close(); err != nil {
+		return nil, err
+	}
+
+	digest := hex.EncodeToString(w.hash.Sum(nil))
+	blob := w.cache.blobPath(digest)
+	if err := os.MkdirAll(filepath.Dir(blob), os.ModePerm); err != nil {
+		return nil, err
+	}
+	// the blob is stored before the entry, so Get never sees an entry without the blob.
+	// Prune keeps recent blobs without an entry, which may be committing.
+	now := time.Now()
+	if err := os.Chtimes(w.file.Name(), now, now); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(w.file.Name(), blob); err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{
+		Key:        w.key,
+		Name:       w.name,
+		Digest:     digest,
+		Size:       w.size,
+		CreatedAt:  now,
+		AccessedAt: now,
+	}
+	if err := w.cache.writeEntry(entry); err != nil {
+		return nil, err
+	}
+
+	if w.cache.maxSize > 0 {
+		if _, err := w.cache.Prune(w.cache.maxSize); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// Abort discards the written contents.
+func (w *Writer) Abort() {
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
+}
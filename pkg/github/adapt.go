@@ -60,3 +60,13 @@ type RepositoryRelease = ggithub.RepositoryRelease
 
 // ReleaseAsset represents a GitHub release asset in a repository.
 type ReleaseAsset = ggithub.ReleaseAsset
+
+// ArchiveFormat is used to define the archive type of repository source.
+type ArchiveFormat = ggithub.ArchiveFormat
+
+const (
+	// Tarball specifies an archive in gzipped tar format.
+	Tarball = ggithub.Tarball
+	// Zipball specifies an archive in zip format.
+	Zipball = ggithub.Zipball
+)
@@ -0,0 +1,153 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/fatih/color"
+	ggithub "github.com/google/go-github/v32/github"
+	"github.com/reactivex/rxgo/v2"
+)
+
+// Artifact represents a GitHub Actions workflow run artifact.
+type Artifact = ggithub.Artifact
+
+// WorkflowRun represents a GitHub Actions workflow run.
+type WorkflowRun = ggithub.WorkflowRun
+
+// ArtifactOptions are parameters to find a workflow run of artifacts.
+type ArtifactOptions struct {
+	// Workflow is the workflow file name or ID.
+	// The latest successful run of the workflow is used.
+	Workflow string
+	// Branch filters workflow runs. (optional)
+	Branch string
+	// RunID is the workflow run ID, which is used instead of the workflow.
+	RunID int64
+}
+
+// ListArtifacts gets the workflow run and its artifacts.
+func (c *Client) ListArtifacts(ctx context.Context,
+	repo Repository,
+	opt *ArtifactOptions,
+) (*WorkflowRun, []*Artifact, error) {
+	if err := repo.valid(); err != nil {
+		return nil, nil, err
+	}
+
+	run, err := c.workflowRun(ctx, repo, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var artifacts []*Artifact
+	listOpt := &ListOptions{PerPage: 100}
+	for {
+		list, resp, err := c.client.Actions.ListWorkflowRunArtifacts(ctx,
+			repo.Owner(), repo.Name(), run.GetID(), listOpt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		artifacts = append(artifacts, list.Artifacts...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpt.Page = resp.NextPage
+	}
+	return run, artifacts, nil
+}
+
+// workflowRun gets the workflow run by ID,
+// or the latest successful run of the workflow.
+func (c *Client) workflowRun(ctx context.Context,
+	repo Repository,
+	opt *ArtifactOptions,
+) (*WorkflowRun, error) {
+	if opt.RunID != 0 {
+		run, _, err := c.client.Actions.GetWorkflowRunByID(ctx, repo.Owner(), repo.Name(), opt.RunID)
+		return run, err
+	}
+	if opt.Workflow == "" {
+		return nil, errors.New("require workflow or run id")
+	}
+
+	listOpt := &ggithub.ListWorkflowRunsOptions{
+		Branch:      opt.Branch,
+		Status:      "success",
+		ListOptions: ListOptions{PerPage: 1},
+	}
+
+	var runs *ggithub.WorkflowRuns
+	var err error
+	if id, perr := strconv.ParseInt(opt.Workflow, 10, 64); perr == nil {
+		runs, _, err = c.client.Actions.ListWorkflowRunsByID(ctx, repo.Owner(), repo.Name(), id, listOpt)
+	} else {
+		runs, _, err = c.client.Actions.ListWorkflowRunsByFileName(ctx, repo.Owner(), repo.Name(), opt.Workflow, listOpt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runs.WorkflowRuns) == 0 {
+		return nil, fmt.Errorf("not found successful workflow run: [workflow: %s, branch: %s]", opt.Workflow, opt.Branch)
+	}
+	return runs.WorkflowRuns[0], nil
+}
+
+// DownloadArtifact downloads a workflow run artifact, which is a zip archive.
+// first returns artifact info as a release asset.
+// second returns download progress info and install result, or error info use a stream.
+// third returns initialize error info.
+// The archive is extracted and picked by the options like a release asset,
+// but it is neither cached nor receipted.
+func (c *Client) DownloadArtifact(ctx context.Context,
+	repo Repository,
+	artifact *Artifact,
+	opt *AssetOptions,
+) (*ReleaseAsset, rxgo.Observable, error) {
+	if err := repo.valid(); err != nil {
+		return nil, nil, err
+	}
+	if artifact.GetExpired() {
+		return nil, nil, fmt.Errorf("expired artifact: %s", artifact.GetName())
+	}
+
+	link, _, err := c.client.Actions.DownloadArtifact(ctx, repo.Owner(), repo.Name(), artifact.GetID(), true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := artifact.GetName() + ".zip"
+	size := int(artifact.GetSizeInBytes())
+	asset := &ReleaseAsset{
+		ID:        artifact.ID,
+		Name:      &name,
+		Size:      &size,
+		CreatedAt: artifact.CreatedAt,
+	}
+	if c.verbose {
+		// the query of the link has a signed token.
+		color.Cyan("artifact dl url:\t%s://%s%s", link.Scheme, link.Host, link.Path)
+	}
+
+	return asset, c.limit(ctx, c.downloadLink(ctx, link, repo, asset, opt)), nil
+}
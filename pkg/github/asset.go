@@ -1,14 +1,135 @@
 package github
 
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/iwaltgen/github-dl/pkg/install"
+)
+
+// StdoutPath is a destination path that writes the asset to the standard output.
+// With a pick, the single picked file is written instead of the asset.
+const StdoutPath = "-"
+
 // AssetOptions are parameters to download an asset file.
 type AssetOptions struct {
-	Tag         string
-	Name        string
-	OS          string
-	OSAlias     []string
-	Arch        string
-	ArchAlias   []string
-	DestPath    string
-	Target      string
+	Tag  string
+	Name string
+	// NameRegex is a regular expression of the asset name. (optional)
+	// It selects every matched asset, instead of the name, os and arch keywords.
+	NameRegex string
+	// NameGlob is a glob pattern of the asset name. (optional)
+	// It selects every matched asset, instead of the name, os and arch keywords.
+	NameGlob string
+	// NameTemplate is a go template of the exact asset name. (optional)
+	// It selects the asset of the rendered name, instead of the name, os and arch keywords.
+	// (see TemplateData)
+	NameTemplate string
+	// Exclude are patterns of asset names to skip before the selection. (optional)
+	// A pattern is a glob, or a regular expression with the "re:" prefix.
+	Exclude   []string
+	OS        string
+	OSAlias   []string
+	Arch      string
+	ArchAlias []string
+	// Libc is the C library of the host: gnu, musl, msvc. (optional)
+	// matched builds are preferred, and static or musl builds are the fallback of gnu.
+	Libc     string
+	DestPath string
+	Target   string
+	// PickPattern is a file name pattern to extract from an archive asset.
+	//
+	// Deprecated: use Picks. It is a single pick without a destination if Picks is empty.
 	PickPattern string
+	// Picks are file name patterns to extract from an archive asset.
+	Picks []Pick
+	// PreservePaths keeps the relative folder structure of the archive for picked files.
+	PreservePaths bool
+	// StripComponents removes the number of leading folders from extracted file paths.
+	StripComponents int
+	// LimitRate is the maximum download speed in bytes per second. (0 is unlimited)
+	// Concurrent downloads with the same limit of a client share the bandwidth.
+	LimitRate int64
+	// Mode is the file mode of the raw asset or picked files.
+	// (0 adds the executable bits to detected executables)
+	Mode os.FileMode
+	// Overwrite is the policy of existing destination files.
+	Overwrite install.Policy
+	// ReplaceFolders replaces a folder which is in the way of an installed file.
+	ReplaceFolders bool
+	// Force downloads and installs even if the install receipt matches the release asset.
+	Force bool
+}
+
+// picks returns the picks, or the pick of the deprecated pick pattern.
+func (o *AssetOptions) picks() []Pick {
+	if len(o.Picks) == 0 && o.PickPattern != "" {
+		return []Pick{{Pattern: o.PickPattern}}
+	}
+	return o.Picks
+}
+
+// byPattern reports whether assets are selected by the name regex or glob.
+func (o *AssetOptions) byPattern() bool {
+	return o.NameRegex != "" || o.NameGlob != ""
+}
+
+// excludeRegexPrefix is the prefix of a regular expression exclude pattern.
+const excludeRegexPrefix = "re:"
+
+// excluder returns a function which returns the exclude pattern matched the asset name, or empty.
+func (o *AssetOptions) excluder() (func(name string) string, error) {
+	matchers := make([]func(name string) bool, 0, len(o.Exclude))
+	for _, pattern := range o.Exclude {
+		if strings.HasPrefix(pattern, excludeRegexPrefix) {
+			re, err := regexp.Compile(strings.TrimPrefix(pattern, excludeRegexPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("exclude regex error: %w", err)
+			}
+			matchers = append(matchers, re.MatchString)
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("exclude glob error: %w", err)
+		}
+		glob := pattern
+		matchers = append(matchers, func(name string) bool {
+			matched, _ := path.Match(glob, name)
+			return matched
+		})
+	}
+
+	return func(name string) string {
+		for i, match := range matchers {
+			if match(name) {
+				return o.Exclude[i]
+			}
+		}
+		return ""
+	}, nil
+}
+
+// Pick is a file name pattern to extract from an archive asset.
+type Pick struct {
+	Pattern string
+	// Destination is the output path relative to the destination path. (optional)
+	// It is a folder if it ends with a separator or the pattern matches several files.
+	Destination string
+}
+
+// InstallResult is the last item of a download stream.
+type InstallResult struct {
+	Asset *ReleaseAsset
+	// Files are installed file paths.
+	Files []string
+	// Kept are existing file paths which are not overwritten.
+	Kept []string
+	// Backups are backup file paths of the overwritten files.
+	Backups []string
+	// Skipped reports the install receipt matched and nothing was downloaded.
+	Skipped bool
 }
@@ -18,40 +18,91 @@ package github
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
-	"path"
 	"path/filepath"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	ggithub "github.com/google/go-github/v32/github"
-	"github.com/mattn/go-zglob"
 	"github.com/reactivex/rxgo/v2"
 	"golang.org/x/oauth2"
 
-	"github.com/iwaltgen/github-dl/pkg/archive"
+	"github.com/iwaltgen/github-dl/pkg/cache"
 )
 
 // Client is a github oauth2 client.
 type Client struct {
-	client  *ggithub.Client
-	verbose bool
+	client   *ggithub.Client
+	api      *http.Client
+	download *http.Client
+	limiters rateLimiters
+	cache    *cache.Cache
+	stall    time.Duration
+	verbose  bool
+
+	// slots bounds the number of concurrent downloads.
+	slots chan struct{}
+
+	// receiptMu serializes receipt files shared by concurrent downloads.
+	receiptMu sync.Mutex
 }
 
 // NewClient creates github client.
 func NewClient(accessToken string, verbose bool) *Client {
-	ctx := context.Background()
+	client, _ := NewClientWithOptions(accessToken, verbose)
+	return client
+}
+
+// NewClientWithOptions creates github client configured by options.
+// API requests and asset downloads share a http transport configured by options.
+// It returns the http transport or enterprise url error of the options.
+func NewClientWithOptions(accessToken string, verbose bool, opts ...Option) (*Client, error) {
+	options := &clientOptions{concurrency: DefaultConcurrency}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	httpClient, err := options.http.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
 	tokenSource := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: accessToken},
 	)
-	return &Client{
-		client:  ggithub.NewClient(oauth2.NewClient(ctx, tokenSource)),
-		verbose: verbose,
+	apiClient := oauth2.NewClient(ctx, tokenSource)
+	apiClient.Timeout = httpClient.Timeout
+
+	githubClient := ggithub.NewClient(apiClient)
+	if options.baseURL != "" {
+		githubClient, err = ggithub.NewEnterpriseClient(options.baseURL, options.baseURL, apiClient)
+		if err != nil {
+			return nil, fmt.Errorf("github enterprise url error: %w", err)
+		}
+	}
+
+	client := &Client{
+		client:   githubClient,
+		api:      apiClient,
+		download: httpClient,
+		cache:    options.cache,
+		verbose:  verbose,
+	}
+	if options.concurrency > 0 {
+		client.slots = make(chan struct{}, options.concurrency)
+	}
+	if options.http != nil {
+		client.stall = options.http.StallTimeout
 	}
+	return client, nil
 }
 
 // ListReleases get release list.
@@ -87,172 +138,385 @@ func (c *Client) GetRelease(ctx context.Context,
 
 // DownloadReleaseAsset downloads a release asset file.
 // first returns release asset info.
-// second returns download progress info or error info use a stream.
+// second returns download progress info and install result, or error info use a stream.
 // third returns initialize error info.
+// The download is skipped if the install receipt in the destination path matches the release asset.
 func (c *Client) DownloadReleaseAsset(ctx context.Context,
 	repo Repository,
 	opt *AssetOptions,
 ) (*ReleaseAsset, rxgo.Observable, error) {
-	if err := repo.valid(); err != nil {
-		return nil, nil, err
-	}
-
-	release, err := c.GetRelease(ctx, repo, opt.Tag)
+	assets, observable, err := c.DownloadReleaseAssets(ctx, repo, []*AssetOptions{opt})
 	if err != nil {
 		return nil, nil, err
 	}
+	return assets[0], observable, nil
+}
 
-	asset := c.findReleaseAsset(release, opt)
-	if asset == nil {
-		err := fmt.Errorf("not found asset: [name: %s, os: %s, arch: %s]", opt.Name, opt.OS, opt.Arch)
+// DownloadReleaseAssets downloads several assets of a release concurrently.
+// The release is fetched once, so every option must have the same tag.
+// first returns release asset info in the order of options.
+// second returns download progress info and install results of every asset, or error info use a stream.
+// third returns initialize error info.
+func (c *Client) DownloadReleaseAssets(ctx context.Context,
+	repo Repository,
+	opts []*AssetOptions,
+) ([]*ReleaseAsset, rxgo.Observable, error) {
+	release, resolutions, err := c.ResolveReleaseAssets(ctx, repo, opts)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	observable, err := c.downloadAsset(asset, opt)
-	return asset, observable, err
+	return c.DownloadResolvedAssets(ctx, repo, release, resolutions)
 }
 
-func (c *Client) findReleaseAsset(release *RepositoryRelease, opt *AssetOptions) *ReleaseAsset {
-	for _, asset := range release.Assets {
-		name := strings.ToLower(asset.GetName())
-		matchedName := strings.Contains(name, strings.ToLower(opt.Name))
+// DownloadResolvedAssets downloads the selected assets of the resolutions of the release.
+// (see ResolveReleaseAssets)
+// first returns the selected release assets.
+// second returns download progress info and install result, or error info use a stream.
+// third returns the selection error info.
+func (c *Client) DownloadResolvedAssets(ctx context.Context,
+	repo Repository,
+	release *RepositoryRelease,
+	resolutions []*Resolution,
+) ([]*ReleaseAsset, rxgo.Observable, error) {
+	assets := make([]*ReleaseAsset, 0, len(resolutions))
+	observables := make([]rxgo.Observable, 0, len(resolutions))
+	found := map[int64]bool{}
+	for _, resolution := range resolutions {
+		matched, opt := resolution.Assets, resolution.opt
+		if resolution.Err != nil {
+			return nil, nil, resolution.Err
+		}
+		if len(matched) > 1 && (opt.Target != "" || opt.DestPath == StdoutPath) {
+			return nil, nil, fmt.Errorf("several assets matched can not use target or stdout: %d assets", len(matched))
+		}
 
-		matchedOS := strings.Contains(name, strings.ToLower(opt.OS))
-		if !matchedOS {
-			for _, v := range opt.OSAlias {
-				if matchedOS = strings.Contains(name, v); matchedOS {
-					break
+		for _, asset := range matched {
+			if found[asset.GetID()] {
+				// assets matched by patterns may overlap.
+				if opt.byPattern() {
+					continue
 				}
+				return nil, nil, fmt.Errorf("duplicate asset: %s", asset.GetName())
 			}
+			found[asset.GetID()] = true
+
+			assets = append(assets, asset)
+			observables = append(observables, c.limit(ctx, c.releaseAsset(ctx, repo, release, asset, opt)))
 		}
+	}
 
-		matchedArch := strings.Contains(name, strings.ToLower(opt.Arch))
-		if !matchedArch {
-			for _, v := range opt.ArchAlias {
-				if matchedArch = strings.Contains(name, v); matchedArch {
-					break
-				}
-			}
+	if len(observables) == 1 {
+		return assets, observables[0], nil
+	}
+	return assets, rxgo.Merge(observables), nil
+}
+
+// limit defers the observable until a download slot of the client is free.
+func (c *Client) limit(ctx context.Context, observable rxgo.Observable) rxgo.Observable {
+	if c.slots == nil {
+		return observable
+	}
+
+	return rxgo.Defer([]rxgo.Producer{func(_ context.Context, next chan<- rxgo.Item) {
+		select {
+		case c.slots <- struct{}{}:
+		case <-ctx.Done():
+			next <- rxgo.Error(ctx.Err())
+			return
 		}
+		defer func() { <-c.slots }()
 
-		if matchedName && matchedOS && matchedArch {
-			return asset
+		for item := range observable.Observe() {
+			next <- item
 		}
-	}
-	return nil
+	}})
 }
 
-func (c *Client) downloadAsset(asset *ReleaseAsset, opt *AssetOptions) (rxgo.Observable, error) {
-	url := asset.GetBrowserDownloadURL()
-	if c.verbose {
-		color.Cyan("release dl url:\t%s", url)
+// releaseAsset returns the installed result if the install receipt matches,
+// otherwise downloads the asset.
+func (c *Client) releaseAsset(ctx context.Context,
+	repo Repository,
+	release *RepositoryRelease,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) rxgo.Observable {
+	if opt.DestPath != StdoutPath && !opt.Force {
+		if receipt, ok := c.installedReceipt(repo, release, asset, opt); ok {
+			files := make([]string, 0, len(receipt.Files))
+			for _, v := range receipt.Files {
+				files = append(files, filepath.Join(opt.DestPath, v))
+			}
+			result := &InstallResult{Asset: asset, Files: files, Skipped: true}
+			return rxgo.Just(result)()
+		}
 	}
+	return c.downloadAsset(ctx, repo, release, asset, opt)
+}
 
-	resp, err := http.Get(url)
+func (c *Client) installedReceipt(repo Repository,
+	release *RepositoryRelease,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) (*Receipt, bool) {
+	c.receiptMu.Lock()
+	receipt, err := readReceipt(receiptPath(repo, opt), asset)
+	c.receiptMu.Unlock()
 	if err != nil {
-		return nil, err
+		if !os.IsNotExist(err) && c.verbose {
+			color.Yellow("receipt error:\t%v", err)
+		}
+		return nil, false
 	}
+	return receipt, receipt != nil && receipt.matches(repo, release, asset, opt.DestPath)
+}
 
-	return rxgo.Defer([]rxgo.Producer{func(ctx context.Context, next chan<- rxgo.Item) {
-		defer resp.Body.Close()
+// downloadAsset fetches the asset through the authenticated API endpoint.
+// the API redirects to the storage host, which is followed by the plain
+// download client so the oauth2 token is not sent to it.
+func (c *Client) downloadAsset(ctx context.Context,
+	repo Repository,
+	release *RepositoryRelease,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) rxgo.Observable {
+	var key cache.Key
+	if c.cache != nil {
+		key = c.cacheKey(repo, asset)
+		entry, blob, err := c.cache.Get(key)
+		if err == nil {
+			return c.cachedAsset(ctx, entry, blob, repo, release, asset, opt)
+		}
+		if err != cache.ErrNotFound && c.verbose {
+			color.Yellow("cache error:\t%v", err)
+		}
+	}
 
-		filename := path.Base(url)
-		destination := filepath.Join(opt.DestPath, filename)
-		tempext := ".ghdownload"
+	if c.verbose {
+		color.Cyan("release dl url:\t%s", asset.GetURL())
+	}
 
-		if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+	return rxgo.Defer([]rxgo.Producer{func(_ context.Context, next chan<- rxgo.Item) {
+		reqCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		body, _, err := c.client.Repositories.DownloadReleaseAsset(reqCtx,
+			repo.Owner(), repo.Name(), asset.GetID(), c.download)
+		if err != nil {
 			next <- rxgo.Error(err)
 			return
 		}
-		file, err := os.Create(destination + tempext)
+		defer body.Close()
+
+		reader, stop := c.bodyReader(ctx, body, cancel, opt)
+		defer stop()
+
+		var cacheWriter *cache.Writer
+		if c.cache != nil {
+			w, err := c.cache.Create(key, asset.GetName())
+			if err == nil {
+				defer w.Abort()
+				cacheWriter = w
+				// the cache writer keeps write errors to itself, so it never fails the download.
+				reader = io.TeeReader(reader, w)
+			} else if c.verbose {
+				color.Yellow("cache error:\t%v", err)
+			}
+		}
+
+		result, err := c.installAsset(ctx, next, reader, repo, release, asset, opt)
 		if err != nil {
 			next <- rxgo.Error(err)
 			return
 		}
-		defer file.Close()
 
-		counter := NewWriteCounter(next, int64(asset.GetSize()))
-		if _, err = io.Copy(file, io.TeeReader(resp.Body, counter)); err != nil {
+		if cacheWriter != nil {
+			c.commitCache(cacheWriter, asset)
+		}
+		next <- rxgo.Of(result)
+	}})
+}
+
+// downloadLink fetches a signed download link with the plain download client,
+// and saves it without the cache and the install receipt.
+func (c *Client) downloadLink(ctx context.Context,
+	link *url.URL,
+	repo Repository,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) rxgo.Observable {
+	return rxgo.Defer([]rxgo.Producer{func(_ context.Context, next chan<- rxgo.Item) {
+		reqCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, link.String(), nil)
+		if err != nil {
 			next <- rxgo.Error(err)
 			return
 		}
 
-		if err := os.Rename(destination+tempext, destination); err != nil {
+		resp, err := c.download.Do(req)
+		if err != nil {
 			next <- rxgo.Error(err)
+			return
 		}
-		defer func() {
-			_ = os.Remove(destination + tempext)
-			_ = os.Remove(destination)
-		}()
-
-		if !archive.Support(filename) {
-			if opt.Target != "" {
-				newDestination := filepath.Join(opt.DestPath, opt.Target)
-				if err := os.Rename(destination, newDestination); err != nil {
-					next <- rxgo.Error(err)
-				}
-			}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			next <- rxgo.Error(fmt.Errorf("download `%s` error: %s", asset.GetName(), resp.Status))
 			return
 		}
+		if resp.ContentLength > 0 {
+			size := int(resp.ContentLength)
+			asset.Size = &size
+		}
 
-		if opt.PickPattern != "" {
-			c.extractFile(next, destination, opt)
+		reader, stop := c.bodyReader(ctx, resp.Body, cancel, opt)
+		defer stop()
+
+		result, err := c.installAsset(ctx, next, reader, repo, nil, asset, opt)
+		if err != nil {
+			next <- rxgo.Error(err)
 			return
 		}
+		next <- rxgo.Of(result)
+	}})
+}
+
+// bodyReader wraps the download body with the stall detection and the rate limit.
+// The stall detection cancels the request, and is stopped by the returned function.
+func (c *Client) bodyReader(ctx context.Context,
+	body io.Reader,
+	cancel context.CancelFunc,
+	opt *AssetOptions,
+) (io.Reader, func()) {
+	reader := body
+	stop := func() {}
+	var stall *stallReader
+	if c.stall > 0 {
+		stall = newStallReader(reader, c.stall, cancel)
+		reader, stop = stall, func() { _ = stall.Close() }
+	}
+	if opt.LimitRate > 0 {
+		reader = newRateReader(ctx, reader, c.limiters.get(opt.LimitRate), stall)
+	}
+	return reader, stop
+}
 
-		newDestination := filepath.Join(opt.DestPath, opt.Target)
-		if err := archive.Unarchive(destination, newDestination); err != nil {
+// cachedAsset saves the asset from the cache blob instead of the network.
+func (c *Client) cachedAsset(ctx context.Context,
+	entry *cache.Entry,
+	blob string,
+	repo Repository,
+	release *RepositoryRelease,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) rxgo.Observable {
+	if c.verbose {
+		color.Cyan("cache hit:\t%s (%s)", entry.Name, entry.Digest)
+	}
+
+	return rxgo.Defer([]rxgo.Producer{func(_ context.Context, next chan<- rxgo.Item) {
+		file, err := os.Open(blob)
+		if err != nil {
+			next <- rxgo.Error(err)
+			return
+		}
+		defer file.Close()
+
+		result, err := c.installAsset(ctx, next, file, repo, release, asset, opt)
+		if err != nil {
 			next <- rxgo.Error(err)
 			return
 		}
-	}}), nil
+		next <- rxgo.Of(result)
+	}})
 }
 
-func (c *Client) extractFile(ch chan<- rxgo.Item, source string, opt *AssetOptions) {
-	tempdir, err := ioutil.TempDir(os.TempDir(), "github-dl")
-	if err != nil {
-		ch <- rxgo.Error(err)
-		return
+func (c *Client) cacheKey(repo Repository, asset *ReleaseAsset) cache.Key {
+	return cache.Key{
+		Host:       c.client.BaseURL.Host,
+		Repository: string(repo),
+		AssetID:    asset.GetID(),
+		UpdatedAt:  asset.GetUpdatedAt().Time,
 	}
-	defer func() {
-		_ = os.RemoveAll(tempdir)
-	}()
+}
 
-	if err := archive.Unarchive(source, tempdir); err != nil {
-		ch <- rxgo.Error(err)
+// commitCache stores the downloaded asset into the cache.
+// cache errors do not fail the download.
+func (c *Client) commitCache(w *cache.Writer, asset *ReleaseAsset) {
+	if size := int64(asset.GetSize()); size > 0 && w.Size() != size {
 		return
 	}
 
-	matches, err := zglob.Glob(filepath.Join(tempdir, "**", opt.PickPattern))
+	entry, err := w.Commit()
 	if err != nil {
-		ch <- rxgo.Error(err)
+		if c.verbose {
+			color.Yellow("cache error:\t%v", err)
+		}
 		return
 	}
 
 	if c.verbose {
-		color.Cyan("pick matches:\t%v", strings.Join(matches, "\n\t\t"))
+		color.Cyan("cache stored:\t%s (%s)", entry.Name, entry.Digest)
 	}
+}
 
-	for i, path := range matches {
-		if opt.Target != "" {
-			suffix := ""
-			if i != 0 {
-				suffix = fmt.Sprintf(".%d", i)
-			}
+// installAsset saves the asset and writes the install receipt.
+// The receipt is written only for release assets. (release is not nil)
+func (c *Client) installAsset(ctx context.Context,
+	ch chan<- rxgo.Item,
+	body io.Reader,
+	repo Repository,
+	release *RepositoryRelease,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) (*InstallResult, error) {
+	hash := sha256.New()
+	committed, err := c.saveAsset(ctx, ch, io.TeeReader(body, hash), asset, opt)
+	if err != nil {
+		return nil, err
+	}
 
-			destination := filepath.Join(opt.DestPath, opt.Target+suffix)
-			if err := os.Rename(path, destination); err != nil {
-				ch <- rxgo.Error(err)
-				return
-			}
-			continue
+	result := &InstallResult{Asset: asset}
+	if committed == nil {
+		return result, nil
+	}
+	result.Files = committed.Files
+	result.Kept = committed.Skipped
+	result.Backups = committed.Backups
+	if release == nil {
+		return result, nil
+	}
+	// kept files are not of the release, so the next run installs them again.
+	if len(committed.Skipped) > 0 || len(committed.Files) == 0 {
+		if c.verbose {
+			color.Yellow("receipt skipped:\t%d files kept", len(committed.Skipped))
 		}
+		return result, nil
+	}
 
-		filename := filepath.Base(path)
-		destination := filepath.Join(opt.DestPath, filename)
-		if err := os.Rename(path, destination); err != nil {
-			ch <- rxgo.Error(err)
-			return
+	receipt := &Receipt{
+		Repository:  string(repo),
+		Tag:         release.GetTagName(),
+		AssetID:     asset.GetID(),
+		AssetName:   asset.GetName(),
+		UpdatedAt:   asset.GetUpdatedAt().Time,
+		Digest:      "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+		Files:       []string{},
+		InstalledAt: time.Now(),
+	}
+	for _, v := range result.Files {
+		if rel, err := filepath.Rel(opt.DestPath, v); err == nil {
+			receipt.Files = append(receipt.Files, filepath.ToSlash(rel))
 		}
 	}
+
+	c.receiptMu.Lock()
+	err = writeReceipt(receiptPath(repo, opt), receipt, opt)
+	c.receiptMu.Unlock()
+	if err != nil && c.verbose {
+		color.Yellow("receipt error:\t%v", err)
+	}
+	return result, nil
 }
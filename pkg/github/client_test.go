@@ -0,0 +1,88 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reactivex/rxgo/v2"
+)
+
+func TestClientLimit(t *testing.T) {
+	const concurrency = 2
+	client, err := NewClientWithOptions("", false, WithConcurrency(concurrency))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, max int32
+	download := rxgo.Defer([]rxgo.Producer{func(_ context.Context, next chan<- rxgo.Item) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		next <- rxgo.Of(n)
+	}})
+
+	ctx := context.Background()
+	var observables []rxgo.Observable
+	for i := 0; i < 3*concurrency; i++ {
+		observables = append(observables, client.limit(ctx, download))
+	}
+
+	var items int
+	for item := range rxgo.Merge(observables).Observe() {
+		if item.E != nil {
+			t.Fatal(item.E)
+		}
+		items++
+	}
+	if items != len(observables) {
+		t.Errorf("items = %d, want %d", items, len(observables))
+	}
+	if max != concurrency {
+		t.Errorf("max concurrent downloads = %d, want %d", max, concurrency)
+	}
+}
+
+func TestClientLimitCanceled(t *testing.T) {
+	client, err := NewClientWithOptions("", false, WithConcurrency(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the slot is taken, so the download waits until the context is done.
+	client.slots <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	item, err := client.limit(ctx, rxgo.Just(1)()).First().Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.E != context.DeadlineExceeded {
+		t.Errorf("error = %v, want %v", item.E, context.DeadlineExceeded)
+	}
+}
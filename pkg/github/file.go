@@ -0,0 +1,272 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	ggithub "github.com/google/go-github/v32/github"
+	"github.com/reactivex/rxgo/v2"
+
+	"github.com/iwaltgen/github-dl/pkg/archive"
+	"github.com/iwaltgen/github-dl/pkg/install"
+)
+
+// RepositoryContent represents a file or folder in a github repository.
+type RepositoryContent = ggithub.RepositoryContent
+
+// DownloadFile downloads a file, or files of a folder recursively, of the repository at the ref.
+// The ref is a tag, branch or commit sha. If it is empty, the default branch is used.
+// first returns the file info as a release asset. (the size is the sum of the files)
+// second returns download progress info and install result, or error info use a stream.
+// third returns initialize error info.
+// Files keep the folder structure under the base name of the path, which is renamed by the target.
+// The destination options are applied like a raw asset, but files are neither cached nor receipted.
+func (c *Client) DownloadFile(ctx context.Context,
+	repo Repository,
+	ref string,
+	fpath string,
+	opt *AssetOptions,
+) (*ReleaseAsset, rxgo.Observable, error) {
+	if err := repo.valid(); err != nil {
+		return nil, nil, err
+	}
+
+	fpath = strings.Trim(path.Clean("/"+fpath), "/")
+	if fpath == "" {
+		return nil, nil, errors.New("require repository file path")
+	}
+
+	files, err := c.listContents(ctx, repo, ref, fpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("not found file: %s", fpath)
+	}
+	if opt.DestPath == StdoutPath && (len(files) != 1 || files[0].GetPath() != fpath) {
+		return nil, nil, fmt.Errorf("file to stdout requires a single file: %s", fpath)
+	}
+
+	var size int
+	for _, v := range files {
+		size += v.GetSize()
+	}
+	name := path.Base(fpath)
+	asset := &ReleaseAsset{Name: &name, Size: &size}
+
+	return asset, c.limit(ctx, rxgo.Defer([]rxgo.Producer{func(_ context.Context, next chan<- rxgo.Item) {
+		result, err := c.saveFiles(ctx, next, repo, ref, fpath, files, asset, opt)
+		if err != nil {
+			next <- rxgo.Error(err)
+			return
+		}
+		next <- rxgo.Of(result)
+	}})), nil
+}
+
+// listContents returns the file, or files of the folder recursively.
+func (c *Client) listContents(ctx context.Context,
+	repo Repository,
+	ref string,
+	fpath string,
+) ([]*RepositoryContent, error) {
+	file, dir, _, err := c.client.Repositories.GetContents(ctx, repo.Owner(), repo.Name(), fpath,
+		&ggithub.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		return []*RepositoryContent{file}, nil
+	}
+
+	var files []*RepositoryContent
+	for _, v := range dir {
+		switch v.GetType() {
+		case "file":
+			files = append(files, v)
+
+		case "dir":
+			sub, err := c.listContents(ctx, repo, ref, v.GetPath())
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, sub...)
+
+		default: // symlink, submodule
+			if c.verbose {
+				color.Yellow("skip content:\t%s (%s)", v.GetPath(), v.GetType())
+			}
+		}
+	}
+	return files, nil
+}
+
+// saveFiles downloads the files into a stage, and commits it into the destination path.
+func (c *Client) saveFiles(ctx context.Context,
+	ch chan<- rxgo.Item,
+	repo Repository,
+	ref string,
+	fpath string,
+	files []*RepositoryContent,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) (*InstallResult, error) {
+	counter := NewWriteCounter(ch, int64(asset.GetSize()))
+	counter.Asset = asset
+
+	if opt.DestPath == StdoutPath {
+		err := c.downloadContent(ctx, counter, repo, ref, fpath, os.Stdout, opt)
+		return &InstallResult{Asset: asset}, err
+	}
+
+	stage, err := install.NewStage(opt.DestPath)
+	if err != nil {
+		return nil, err
+	}
+	defer stage.Discard()
+
+	for _, v := range files {
+		output := stage.Path(contentPath(fpath, v.GetPath(), opt.Target))
+		if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := c.saveContent(ctx, counter, repo, ref, v.GetPath(), output, opt); err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.Mode != 0 {
+		err = stage.Chmod(opt.Mode)
+	} else {
+		err = stage.MarkExecutables()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	committed, err := stage.Commit(&install.CommitOptions{
+		Policy:         opt.Overwrite,
+		ReplaceFolders: opt.ReplaceFolders,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &InstallResult{
+		Asset:   asset,
+		Files:   committed.Files,
+		Kept:    committed.Skipped,
+		Backups: committed.Backups,
+	}, nil
+}
+
+func (c *Client) saveContent(ctx context.Context,
+	counter *WriteCounter,
+	repo Repository,
+	ref string,
+	fpath string,
+	output string,
+	opt *AssetOptions,
+) error {
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := c.downloadContent(ctx, counter, repo, ref, fpath, file, opt); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// escapePath escapes each segment of the slash separated path. (e.g. `a:b/c d` to `a:b/c%20d`)
+func escapePath(fpath string) string {
+	segments := strings.Split(fpath, "/")
+	for i, v := range segments {
+		segments[i] = url.PathEscape(v)
+	}
+	return strings.Join(segments, "/")
+}
+
+// downloadContent writes the raw content of the file through the authenticated API endpoint.
+func (c *Client) downloadContent(ctx context.Context,
+	counter *WriteCounter,
+	repo Repository,
+	ref string,
+	fpath string,
+	w io.Writer,
+	opt *AssetOptions,
+) error {
+	u := fmt.Sprintf("repos/%s/%s/contents/%s", repo.Owner(), repo.Name(), escapePath(fpath))
+	if ref != "" {
+		u += "?ref=" + url.QueryEscape(ref)
+	}
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3.raw")
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := c.api.Do(req.WithContext(reqCtx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := ggithub.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	reader, stop := c.bodyReader(ctx, resp.Body, cancel, opt)
+	defer stop()
+
+	_, err = io.Copy(w, io.TeeReader(archive.NewContextReader(ctx, reader), counter))
+	return err
+}
+
+// contentPath returns the output path of the file relative to the destination path.
+// The path is relative to the parent of the requested path, whose base name is renamed by the target.
+func contentPath(requested, fpath, target string) string {
+	rel := fpath
+	if parent := path.Dir(requested); parent != "." {
+		rel = strings.TrimPrefix(fpath, parent+"/")
+	}
+
+	if target != "" {
+		parts := strings.SplitN(rel, "/", 2)
+		parts[0] = target
+		rel = strings.Join(parts, "/")
+	}
+	return filepath.FromSlash(rel)
+}
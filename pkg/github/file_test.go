@@ -0,0 +1,37 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import "testing"
+
+func TestEscapePath(t *testing.T) {
+	tests := []struct {
+		fpath string
+		want  string
+	}{
+		{fpath: "script/install.sh", want: "script/install.sh"},
+		{fpath: "file a:b/x", want: "file%20a:b/x"},
+		{fpath: "a:b", want: "a:b"},
+		{fpath: "docs/100%/a?b#c", want: "docs/100%25/a%3Fb%23c"},
+	}
+
+	for _, tt := range tests {
+		if got := escapePath(tt.fpath); got != tt.want {
+			t.Errorf("escapePath(%q) = %q, want %q", tt.fpath, got, tt.want)
+		}
+	}
+}
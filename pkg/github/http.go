@@ -0,0 +1,173 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrStalled is returned when a download receives no data for the stall timeout.
+var ErrStalled = errors.New("download stalled")
+
+// HTTPOptions are parameters of the http transport shared by API requests and asset downloads.
+type HTTPOptions struct {
+	// CAFile is a PEM bundle of certificates trusted in addition to the system pool.
+	CAFile string
+	// ProxyURL is the proxy server. (the environment proxy settings are used if empty)
+	ProxyURL string
+	// InsecureSkipVerify disables TLS certificate verification. (lab use only)
+	InsecureSkipVerify bool
+	// ConnectTimeout is the maximum time to establish a connection.
+	ConnectTimeout time.Duration
+	// IdleTimeout is the maximum time an idle keep-alive connection remains open.
+	IdleTimeout time.Duration
+	// Timeout is the maximum time of a request, including reading the response body.
+	Timeout time.Duration
+	// StallTimeout aborts a download when no bytes arrive for the duration.
+	StallTimeout time.Duration
+}
+
+func (o *HTTPOptions) httpClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o == nil {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if o.ConnectTimeout > 0 {
+		dialer.Timeout = o.ConnectTimeout
+		transport.TLSHandshakeTimeout = o.ConnectTimeout
+	}
+	transport.DialContext = dialer.DialContext
+
+	if o.IdleTimeout > 0 {
+		transport.IdleConnTimeout = o.IdleTimeout
+	}
+	if o.StallTimeout > 0 {
+		transport.ResponseHeaderTimeout = o.StallTimeout
+	}
+
+	if o.ProxyURL != "" {
+		proxyURL, err := url.Parse(o.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy url error: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   o.Timeout,
+	}, nil
+}
+
+func (o *HTTPOptions) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipVerify, // nolint:gosec
+	}
+	if o.CAFile == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(o.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read ca file error: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in ca file: %s", o.CAFile)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
+
+// stallReader is an io.Reader that cancels the download
+// when no bytes arrive for the timeout.
+type stallReader struct {
+	r       io.Reader
+	timeout time.Duration
+	timer   *time.Timer
+
+	mu      sync.Mutex
+	stalled bool
+}
+
+func newStallReader(r io.Reader, timeout time.Duration, cancel context.CancelFunc) *stallReader {
+	s := &stallReader{r: r, timeout: timeout}
+	s.timer = time.AfterFunc(timeout, func() {
+		s.mu.Lock()
+		s.stalled = true
+		s.mu.Unlock()
+		cancel()
+	})
+	return s
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.timer.Reset(s.timeout)
+	}
+	if err != nil && err != io.EOF {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.stalled {
+			return n, fmt.Errorf("%w: no data received for %v", ErrStalled, s.timeout)
+		}
+	}
+	return n, err
+}
+
+// pause stops the stall timer while the download waits for another reason. (e.g. the rate limit)
+func (s *stallReader) pause() {
+	s.timer.Stop()
+}
+
+// resume restarts the stall timer after a pause.
+func (s *stallReader) resume() {
+	s.timer.Reset(s.timeout)
+}
+
+// Close stops the stall timer.
+func (s *stallReader) Close() error {
+	s.timer.Stop()
+	return nil
+}
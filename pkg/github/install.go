@@ -0,0 +1,376 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-zglob"
+	"github.com/reactivex/rxgo/v2"
+
+	"github.com/iwaltgen/github-dl/pkg/archive"
+	"github.com/iwaltgen/github-dl/pkg/install"
+)
+
+// saveAsset writes the asset body into the destination path.
+// outputs are prepared in a stage folder of the destination path,
+// and moved into the destination only when every step succeeds.
+// Existing files are handled by the overwrite policy of the options.
+// It returns nil result when the asset is written to the standard output.
+func (c *Client) saveAsset(ctx context.Context,
+	ch chan<- rxgo.Item,
+	body io.Reader,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) (*install.CommitResult, error) {
+	if opt.DestPath == StdoutPath {
+		return nil, c.writeAsset(ctx, ch, body, asset, opt)
+	}
+
+	stage, err := install.NewStage(opt.DestPath)
+	if err != nil {
+		return nil, err
+	}
+	defer stage.Discard()
+
+	if err := c.stageAsset(ctx, ch, body, asset, opt, stage); err != nil {
+		return nil, err
+	}
+	if err := c.stageModes(asset, opt, stage); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return stage.Commit(&install.CommitOptions{
+		Policy:         opt.Overwrite,
+		ReplaceFolders: opt.ReplaceFolders,
+	})
+}
+
+// stageAsset downloads the asset and prepares the outputs in the stage.
+func (c *Client) stageAsset(ctx context.Context,
+	ch chan<- rxgo.Item,
+	body io.Reader,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+	stage *install.Stage,
+) error {
+	filename := asset.GetName()
+	source := filepath.Join(stage.WorkDir(), filename)
+	if err := writeFile(ctx, ch, body, asset, source); err != nil {
+		return err
+	}
+
+	if !archive.Support(filename) {
+		if opt.Target != "" {
+			filename = opt.Target
+		}
+		return os.Rename(source, stage.Path(filename))
+	}
+
+	if len(opt.picks()) > 0 {
+		return c.stagePicks(ctx, source, opt, stage)
+	}
+
+	if opt.StripComponents == 0 {
+		_, err := archive.Unarchive(ctx, source, stage.Path(opt.Target))
+		return err
+	}
+	return c.stageStripped(ctx, source, opt, stage)
+}
+
+// stageStripped unarchives the source and moves the files into the stage
+// without the leading folders.
+func (c *Client) stageStripped(ctx context.Context,
+	source string,
+	opt *AssetOptions,
+	stage *install.Stage,
+) error {
+	folder := filepath.Join(stage.WorkDir(), "extract")
+	files, err := archive.Unarchive(ctx, source, folder)
+	if err != nil {
+		return err
+	}
+
+	for _, fpath := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(folder, fpath)
+		if err != nil {
+			return err
+		}
+		rel, ok := stripComponents(rel, opt.StripComponents)
+		if !ok {
+			continue
+		}
+
+		output := stage.Path(filepath.Join(opt.Target, rel))
+		if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.Rename(fpath, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripComponents removes the leading folders of the relative path.
+// It returns false if nothing is left.
+func stripComponents(rel string, count int) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) <= count {
+		return "", false
+	}
+	return filepath.Join(parts[count:]...), true
+}
+
+// stageModes sets the mode of the raw asset or picked files if the mode is given,
+// otherwise adds the executable bits to the detected executables.
+func (c *Client) stageModes(asset *ReleaseAsset, opt *AssetOptions, stage *install.Stage) error {
+	picked := !archive.Support(asset.GetName()) || len(opt.picks()) > 0
+	if opt.Mode != 0 && picked {
+		return stage.Chmod(opt.Mode)
+	}
+	return stage.MarkExecutables()
+}
+
+// stagePicks unarchives the source and moves the picked files into the stage.
+func (c *Client) stagePicks(ctx context.Context,
+	source string,
+	opt *AssetOptions,
+	stage *install.Stage,
+) error {
+	folder := filepath.Join(stage.WorkDir(), "extract")
+	if _, err := archive.Unarchive(ctx, source, folder); err != nil {
+		return err
+	}
+
+	targets := map[string]string{}
+	for _, pick := range opt.picks() {
+		matches, err := c.pickFiles(folder, pick.Pattern)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range matches {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if movedWithFolder(path, targets) {
+				continue
+			}
+
+			rel, err := pickDestination(folder, path, len(targets), len(matches), pick, opt)
+			if err != nil {
+				return err
+			}
+			if prev, ok := targets[rel]; ok {
+				return fmt.Errorf("duplicate pick destination `%s`: %s, %s",
+					rel, archivePath(folder, prev), archivePath(folder, path))
+			}
+
+			output := stage.Path(rel)
+			if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+				return err
+			}
+			if err := os.Rename(path, output); err != nil {
+				return err
+			}
+			targets[rel] = path
+		}
+	}
+	return nil
+}
+
+// movedWithFolder reports whether the path is in a folder already picked.
+func movedWithFolder(path string, targets map[string]string) bool {
+	for _, picked := range targets {
+		if strings.HasPrefix(path, picked+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// archivePath returns the path relative to the extracted folder.
+func archivePath(folder, path string) string {
+	if rel, err := filepath.Rel(folder, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// pickDestination returns the output path of a picked file relative to the destination path.
+// index is the number of files picked before, which suffixes the legacy target name.
+func pickDestination(folder, path string, index, count int, pick Pick, opt *AssetOptions) (string, error) {
+	name := filepath.Base(path)
+	if opt.PreservePaths {
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return "", err
+		}
+		if rel, ok := stripComponents(rel, opt.StripComponents); ok {
+			name = rel
+		}
+	}
+
+	if pick.Destination == "" {
+		if opt.Target == "" {
+			return name, nil
+		}
+
+		suffix := ""
+		if index != 0 {
+			suffix = fmt.Sprintf(".%d", index)
+		}
+		return opt.Target + suffix, nil
+	}
+
+	rel := filepath.Join(pick.Destination, name)
+	dir := strings.HasSuffix(pick.Destination, "/") || strings.HasSuffix(pick.Destination, string(filepath.Separator))
+	if !dir && count == 1 {
+		rel = filepath.Clean(pick.Destination)
+	}
+	if !localPath(rel) {
+		return "", fmt.Errorf("pick destination `%s` error: outside of the destination path", pick.Destination)
+	}
+	return rel, nil
+}
+
+// localPath reports whether the path is relative and stays in the folder it is relative to.
+func localPath(rel string) bool {
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" || strings.HasPrefix(rel, string(filepath.Separator)) {
+		return false
+	}
+	clean := filepath.Clean(rel)
+	return clean != "." && clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+// writeAsset writes the asset body, or the single picked file of it, to the standard output.
+func (c *Client) writeAsset(ctx context.Context,
+	ch chan<- rxgo.Item,
+	body io.Reader,
+	asset *ReleaseAsset,
+	opt *AssetOptions,
+) error {
+	picks := opt.picks()
+	if len(picks) == 0 {
+		counter := NewWriteCounter(ch, int64(asset.GetSize()))
+		counter.Asset = asset
+		_, err := io.Copy(os.Stdout, io.TeeReader(archive.NewContextReader(ctx, body), counter))
+		return err
+	}
+
+	filename := asset.GetName()
+	if !archive.Support(filename) {
+		return fmt.Errorf("pick requires an archive asset: %s", filename)
+	}
+	if len(picks) != 1 {
+		return fmt.Errorf("pick to stdout requires a single pattern: %d patterns", len(picks))
+	}
+	pattern := picks[0].Pattern
+
+	tempdir, err := ioutil.TempDir(os.TempDir(), "github-dl")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempdir)
+	}()
+
+	source := filepath.Join(tempdir, filename)
+	if err := writeFile(ctx, ch, body, asset, source); err != nil {
+		return err
+	}
+
+	folder := filepath.Join(tempdir, "extract")
+	if _, err := archive.Unarchive(ctx, source, folder); err != nil {
+		return err
+	}
+
+	matches, err := c.pickFiles(folder, pattern)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, v := range matches {
+		if info, err := os.Stat(v); err == nil && info.Mode().IsRegular() {
+			files = append(files, v)
+		}
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("pick to stdout requires a single file: %d files matched `%s`", len(files), pattern)
+	}
+
+	picked, err := os.Open(files[0])
+	if err != nil {
+		return err
+	}
+	defer picked.Close()
+
+	_, err = io.Copy(os.Stdout, archive.NewContextReader(ctx, picked))
+	return err
+}
+
+// pickFiles returns paths in the folder matched the pattern.
+func (c *Client) pickFiles(folder, pattern string) ([]string, error) {
+	matches, err := zglob.Glob(filepath.Join(folder, "**", pattern))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("not found pick pattern in archive: %s", pattern)
+	}
+
+	if c.verbose {
+		color.Cyan("pick matches:\t%v", strings.Join(matches, "\n\t\t"))
+	}
+	return matches, nil
+}
+
+// writeFile writes the body into the file and reports the download progress.
+func writeFile(ctx context.Context,
+	ch chan<- rxgo.Item,
+	body io.Reader,
+	asset *ReleaseAsset,
+	fpath string,
+) error {
+	file, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	counter := NewWriteCounter(ch, int64(asset.GetSize()))
+	counter.Asset = asset
+	if _, err := io.Copy(file, io.TeeReader(archive.NewContextReader(ctx, body), counter)); err != nil {
+		return err
+	}
+	return file.Close()
+}
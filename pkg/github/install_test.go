@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPickDestination(t *testing.T) {
+	folder := filepath.FromSlash("/stage/work/extract")
+	path := filepath.Join(folder, "tool-1.0", "bin", "tool")
+
+	tests := []struct {
+		name    string
+		pick    Pick
+		opt     *AssetOptions
+		count   int
+		want    string
+		wantErr bool
+	}{
+		{name: "name", pick: Pick{Pattern: "tool"}, opt: &AssetOptions{}, count: 1, want: "tool"},
+		{name: "target", pick: Pick{Pattern: "tool"}, opt: &AssetOptions{Target: "t"}, count: 1, want: "t"},
+		{
+			name:  "preserve paths",
+			pick:  Pick{Pattern: "tool"},
+			opt:   &AssetOptions{PreservePaths: true, StripComponents: 1},
+			count: 1,
+			want:  filepath.Join("bin", "tool"),
+		},
+		{name: "file", pick: Pick{Pattern: "tool", Destination: "bin/t"}, opt: &AssetOptions{}, count: 1, want: filepath.Join("bin", "t")},
+		{name: "folder", pick: Pick{Pattern: "tool", Destination: "bin/"}, opt: &AssetOptions{}, count: 1, want: filepath.Join("bin", "tool")},
+		{name: "several", pick: Pick{Pattern: "*", Destination: "bin"}, opt: &AssetOptions{}, count: 2, want: filepath.Join("bin", "tool")},
+		{name: "inner parent", pick: Pick{Pattern: "tool", Destination: "a/../t"}, opt: &AssetOptions{}, count: 1, want: "t"},
+		{name: "parent", pick: Pick{Pattern: "tool", Destination: "../../bin/tool"}, opt: &AssetOptions{}, count: 1, wantErr: true},
+		{name: "parent folder", pick: Pick{Pattern: "tool", Destination: "../"}, opt: &AssetOptions{}, count: 1, wantErr: true},
+		{name: "absolute", pick: Pick{Pattern: "tool", Destination: "/usr/local/bin/tool"}, opt: &AssetOptions{}, count: 1, wantErr: true},
+		{name: "destination itself", pick: Pick{Pattern: "tool", Destination: "."}, opt: &AssetOptions{}, count: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickDestination(folder, path, 0, tt.count, tt.pick, tt.opt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pickDestination() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("pickDestination() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import "github.com/iwaltgen/github-dl/pkg/cache"
+
+// Option configures the client.
+type Option func(*clientOptions)
+
+// DefaultConcurrency is the default maximum number of concurrent downloads.
+const DefaultConcurrency = 4
+
+type clientOptions struct {
+	http        *HTTPOptions
+	baseURL     string
+	cache       *cache.Cache
+	concurrency int
+}
+
+// WithHTTPOptions sets the http transport options of the client.
+func WithHTTPOptions(opt *HTTPOptions) Option {
+	return func(o *clientOptions) {
+		o.http = opt
+	}
+}
+
+// WithBaseURL sets the GitHub Enterprise Server url. (e.g. https://github.example.com/)
+// API requests and asset downloads use the `api/v3/` endpoint of the url.
+func WithBaseURL(baseURL string) Option {
+	return func(o *clientOptions) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithCache sets the download cache of release assets.
+// Repeat downloads of an unchanged asset are served from the cache.
+func WithCache(c *cache.Cache) Option {
+	return func(o *clientOptions) {
+		o.cache = c
+	}
+}
+
+// WithConcurrency sets the maximum number of concurrent downloads of the client.
+// (default: DefaultConcurrency)
+func WithConcurrency(n int) Option {
+	return func(o *clientOptions) {
+		o.concurrency = n
+	}
+}
@@ -13,6 +13,7 @@ type Progress interface {
 
 // DownloadProgress is the current downloaded size of a file.
 type DownloadProgress struct {
+	Asset    *ReleaseAsset
 	Total    int64
 	Received int64
 }
@@ -25,6 +26,7 @@ func (p *DownloadProgress) Percentage() float64 {
 // WriteCounter counts the number of bytes written to it. It implements to the io.Writer interface
 // and we can pass this into io.TeeReader() which will report progress on each write cycle.
 type WriteCounter struct {
+	Asset   *ReleaseAsset
 	Total   int64
 	Written int64
 	ch      chan<- rxgo.Item
@@ -42,6 +44,7 @@ func (w *WriteCounter) Write(p []byte) (int, error) {
 	n := len(p)
 	w.Written += int64(n)
 	w.ch <- rxgo.Of(&DownloadProgress{
+		Asset:    w.Asset,
 		Total:    w.Total,
 		Received: w.Written,
 	})
@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// rateChunkSize is the maximum bytes read at once by a limited reader.
+// small chunks let concurrent downloads share the bandwidth fairly.
+const rateChunkSize = 32 * 1024
+
+// rateLimiters are bandwidth limiters shared by downloads of the same client.
+type rateLimiters struct {
+	mu       sync.Mutex
+	limiters map[int64]*rate.Limiter
+}
+
+// get returns the limiter of bytes per second.
+func (l *rateLimiters) get(bytesPerSec int64) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limiters == nil {
+		l.limiters = map[int64]*rate.Limiter{}
+	}
+	if limiter, ok := l.limiters[bytesPerSec]; ok {
+		return limiter
+	}
+
+	burst := rateChunkSize
+	if bytesPerSec < int64(burst) {
+		burst = int(bytesPerSec)
+	}
+	limiter := rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+	l.limiters[bytesPerSec] = limiter
+	return limiter
+}
+
+// rateReader is an io.Reader that waits the limiter for read bytes.
+// The stall timer of the reader is paused while waiting the limiter.
+type rateReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+	stall   *stallReader
+}
+
+func newRateReader(ctx context.Context, r io.Reader, limiter *rate.Limiter, stall *stallReader) io.Reader {
+	return &rateReader{ctx: ctx, r: r, limiter: limiter, stall: stall}
+}
+
+func (r *rateReader) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.wait(n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (r *rateReader) wait(n int) error {
+	if r.stall != nil {
+		r.stall.pause()
+		defer r.stall.resume()
+	}
+	return r.limiter.WaitN(r.ctx, n)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// blockReader returns the data, and then blocks until the context is done.
+type blockReader struct {
+	ctx  context.Context
+	data *bytes.Reader
+}
+
+func (r *blockReader) Read(p []byte) (int, error) {
+	if r.data.Len() > 0 {
+		return r.data.Read(p)
+	}
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func TestRateReaderStallTimeout(t *testing.T) {
+	const limit = 16 * 1024
+	data := make([]byte, 2*limit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the second chunk waits the limiter for a second, longer than the stall timeout.
+	stall := newStallReader(bytes.NewReader(data), 200*time.Millisecond, cancel)
+	defer stall.Close()
+	reader := newRateReader(ctx, stall, rate.NewLimiter(limit, limit), stall)
+
+	n, err := io.Copy(ioutil.Discard, reader)
+	if err != nil {
+		t.Fatalf("copy error = %v, want nil", err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("copied = %d, want %d", n, len(data))
+	}
+}
+
+func TestRateReaderStalled(t *testing.T) {
+	const limit = 16 * 1024
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	body := &blockReader{ctx: ctx, data: bytes.NewReader(make([]byte, limit))}
+	stall := newStallReader(body, 200*time.Millisecond, cancel)
+	defer stall.Close()
+	reader := newRateReader(ctx, stall, rate.NewLimiter(limit, limit), stall)
+
+	if _, err := io.Copy(ioutil.Discard, reader); err == nil {
+		t.Fatal("copy error = nil, want stalled")
+	}
+}
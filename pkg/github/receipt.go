@@ -0,0 +1,173 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iwaltgen/github-dl/pkg/install"
+)
+
+// receiptDir is the folder of install receipts in the destination path.
+const receiptDir = ".github-dl"
+
+// Receipt records an installed release asset.
+// A matched receipt skips the download of the same release asset.
+type Receipt struct {
+	Repository  string    `json:"repository"`
+	Tag         string    `json:"tag"`
+	AssetID     int64     `json:"asset_id"`
+	AssetName   string    `json:"asset_name"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Digest      string    `json:"digest"`
+	Files       []string  `json:"files"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// matches reports whether the receipt is of the release asset and the installed files exist.
+func (r *Receipt) matches(repo Repository, release *RepositoryRelease, asset *ReleaseAsset, destPath string) bool {
+	if !strings.EqualFold(r.Repository, string(repo)) ||
+		r.Tag != release.GetTagName() ||
+		r.AssetID != asset.GetID() ||
+		!r.UpdatedAt.Equal(asset.GetUpdatedAt().Time) {
+		return false
+	}
+
+	for _, file := range r.Files {
+		if _, err := os.Stat(filepath.Join(destPath, file)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// receiptPath returns the receipt file path of the download options.
+// the path does not depend on the release, so an upgrade replaces the receipt.
+// the receipt file of patterns has a receipt of each matched asset.
+func receiptPath(repo Repository, opt *AssetOptions) string {
+	key := strings.Join([]string{
+		strings.ToLower(string(repo)),
+		opt.Name, opt.OS, opt.Arch, opt.Target,
+	}, "\n")
+	if opt.Libc != "" {
+		key += "\nlibc=" + opt.Libc
+	}
+	for _, v := range opt.Exclude {
+		key += "\nexclude=" + v
+	}
+	if opt.NameTemplate != "" {
+		key += "\ntemplate=" + opt.NameTemplate
+	}
+	if opt.byPattern() {
+		key += "\n" + opt.NameRegex + "\n" + opt.NameGlob
+	}
+	for _, pick := range opt.picks() {
+		key += "\n" + pick.Pattern + "=" + pick.Destination
+	}
+	if opt.PreservePaths {
+		key += "\npreserve-paths"
+	}
+	if opt.StripComponents > 0 {
+		key += fmt.Sprintf("\nstrip-components=%d", opt.StripComponents)
+	}
+	// the install options change the installed files of the same release asset.
+	if opt.Mode != 0 {
+		key += fmt.Sprintf("\nmode=%o", opt.Mode)
+	}
+	if opt.Overwrite != install.Overwrite {
+		key += fmt.Sprintf("\noverwrite=%d", opt.Overwrite)
+	}
+	if opt.ReplaceFolders {
+		key += "\nreplace-folders"
+	}
+	sum := sha256.Sum256([]byte(key))
+	name := fmt.Sprintf("%s.%s.%s.json", repo.Owner(), repo.Name(), hex.EncodeToString(sum[:4]))
+	return filepath.Join(opt.DestPath, receiptDir, name)
+}
+
+// readReceipt returns the receipt of the asset in the receipt file, or nil.
+func readReceipt(fpath string, asset *ReleaseAsset) (*Receipt, error) {
+	receipts, err := readReceipts(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range receipts {
+		if v.AssetName == asset.GetName() {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// readReceipts returns the receipt, or the receipts of patterns in the receipt file.
+func readReceipts(fpath string) ([]*Receipt, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var receipts []*Receipt
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &receipts)
+	} else {
+		receipt := &Receipt{}
+		err = json.Unmarshal(data, receipt)
+		receipts = []*Receipt{receipt}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse receipt `%s` error: %w", fpath, err)
+	}
+	return receipts, nil
+}
+
+// writeReceipt writes the receipt of the asset.
+// the receipt of patterns is merged with the receipts of the other assets of the same release.
+func writeReceipt(fpath string, receipt *Receipt, opt *AssetOptions) error {
+	var v interface{} = receipt
+	if opt.byPattern() {
+		receipts := []*Receipt{}
+		prev, err := readReceipts(fpath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, p := range prev {
+			if p.Tag == receipt.Tag && p.AssetName != receipt.AssetName {
+				receipts = append(receipts, p)
+			}
+		}
+		v = append(receipts, receipt)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fpath, data, 0644)
+}
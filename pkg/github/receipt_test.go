@@ -0,0 +1,160 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/reactivex/rxgo/v2"
+
+	"github.com/iwaltgen/github-dl/pkg/install"
+)
+
+func TestPatternReceipt(t *testing.T) {
+	dest, err := ioutil.TempDir("", "github-dl-receipt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	repo := Repository("owner/tool")
+	opt := &AssetOptions{NameGlob: "tool_*_linux_*.tar.gz", DestPath: dest}
+	fpath := receiptPath(repo, opt)
+
+	for _, v := range []struct{ tag, name string }{
+		{"v1.2.0", "tool_1.2.0_linux_amd64.tar.gz"},
+		{"v1.2.0", "tool_1.2.0_linux_arm64.tar.gz"},
+		{"v1.3.0", "tool_1.3.0_linux_amd64.tar.gz"},
+		{"v1.3.0", "tool_1.3.0_linux_arm64.tar.gz"},
+		{"v1.3.0", "tool_1.3.0_linux_arm64.tar.gz"},
+	} {
+		receipt := &Receipt{Repository: string(repo), Tag: v.tag, AssetName: v.name}
+		if err := writeReceipt(receiptPath(repo, opt), receipt, opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dest, receiptDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != fpath {
+		t.Fatalf("receipt files = %v, want [%s]", files, fpath)
+	}
+
+	receipts, err := readReceipts(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range receipts {
+		names = append(names, v.Tag+" "+v.AssetName)
+	}
+	want := []string{"v1.3.0 tool_1.3.0_linux_amd64.tar.gz", "v1.3.0 tool_1.3.0_linux_arm64.tar.gz"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("receipts = %v, want %v", names, want)
+	}
+
+	name := "tool_1.2.0_linux_amd64.tar.gz"
+	if receipt, err := readReceipt(fpath, &ReleaseAsset{Name: &name}); err != nil || receipt != nil {
+		t.Errorf("readReceipt(%s) = %v, %v, want nil", name, receipt, err)
+	}
+}
+
+func TestKeywordReceipt(t *testing.T) {
+	dest, err := ioutil.TempDir("", "github-dl-receipt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	repo := Repository("owner/tool")
+	opt := &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64", DestPath: dest}
+	fpath := receiptPath(repo, opt)
+
+	for _, v := range []string{"tool_1.2.0_linux_amd64.tar.gz", "tool_1.3.0_linux_amd64.tar.gz"} {
+		if err := writeReceipt(fpath, &Receipt{Repository: string(repo), AssetName: v}, opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	name := "tool_1.3.0_linux_amd64.tar.gz"
+	receipt, err := readReceipt(fpath, &ReleaseAsset{Name: &name})
+	if err != nil || receipt == nil || receipt.AssetName != name {
+		t.Errorf("readReceipt(%s) = %v, %v", name, receipt, err)
+	}
+}
+
+func TestNoClobberReceipt(t *testing.T) {
+	dest, err := ioutil.TempDir("", "github-dl-receipt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	if err := ioutil.WriteFile(filepath.Join(dest, "tool"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := Repository("owner/tool")
+	opt := &AssetOptions{Name: "tool", DestPath: dest, Overwrite: install.NoClobber}
+	tag, name, size := "v1.3.0", "tool", 3
+	release := &RepositoryRelease{TagName: &tag}
+	asset := &ReleaseAsset{Name: &name, Size: &size}
+
+	ch := make(chan rxgo.Item, 64)
+	result, err := NewClient("", false).installAsset(context.Background(), ch, bytes.NewBufferString("new"), repo, release, asset, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Kept) != 1 {
+		t.Fatalf("kept = %v, want [%s]", result.Kept, filepath.Join(dest, "tool"))
+	}
+
+	// the kept file is not of the release, so the receipt must not match.
+	if _, err := os.Stat(receiptPath(repo, opt)); !os.IsNotExist(err) {
+		t.Errorf("receipt is written with kept files: %v", err)
+	}
+}
+
+func TestReceiptPathInstallOptions(t *testing.T) {
+	repo := Repository("owner/tool")
+	base := AssetOptions{Name: "tool", DestPath: "dest"}
+
+	paths := map[string]string{}
+	for name, fn := range map[string]func(*AssetOptions){
+		"default":         func(*AssetOptions) {},
+		"mode":            func(o *AssetOptions) { o.Mode = 0755 },
+		"no-clobber":      func(o *AssetOptions) { o.Overwrite = install.NoClobber },
+		"backup":          func(o *AssetOptions) { o.Overwrite = install.Backup },
+		"numbered-backup": func(o *AssetOptions) { o.Overwrite = install.NumberedBackup },
+		"replace-folders": func(o *AssetOptions) { o.ReplaceFolders = true },
+	} {
+		opt := base
+		fn(&opt)
+		fpath := receiptPath(repo, &opt)
+		if other, ok := paths[fpath]; ok {
+			t.Errorf("receipt path of %s is the same as %s", name, other)
+		}
+		paths[fpath] = name
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Resolution is the asset selection result of asset options.
+type Resolution struct {
+	Name       string       `json:"name,omitempty"`
+	Regex      string       `json:"regex,omitempty"`
+	Glob       string       `json:"glob,omitempty"`
+	Template   string       `json:"template,omitempty"`
+	Exclude    []string     `json:"exclude,omitempty"`
+	OS         string       `json:"os,omitempty"`
+	Arch       string       `json:"arch,omitempty"`
+	Libc       string       `json:"libc,omitempty"`
+	Candidates []*Candidate `json:"candidates"`
+	Selected   []string     `json:"selected"`
+	Error      string       `json:"error,omitempty"`
+
+	// Assets are the selected assets.
+	Assets []*ReleaseAsset `json:"-"`
+	// Err is the selection error. (e.g. *AmbiguousError)
+	Err error `json:"-"`
+
+	opt *AssetOptions
+}
+
+// Select replaces the selection with the asset, and clears the selection error.
+func (r *Resolution) Select(asset *ReleaseAsset) {
+	r.Assets = []*ReleaseAsset{asset}
+	r.Selected = []string{asset.GetName()}
+	r.Err, r.Error = nil, ""
+}
+
+// ResolveReleaseAssets resolves the release and assets of the options without downloading.
+// first returns the release.
+// second returns the selection result of each options, which may have a selection error.
+// third returns the release or options error info.
+func (c *Client) ResolveReleaseAssets(ctx context.Context,
+	repo Repository,
+	opts []*AssetOptions,
+) (*RepositoryRelease, []*Resolution, error) {
+	if err := repo.valid(); err != nil {
+		return nil, nil, err
+	}
+	if len(opts) == 0 {
+		return nil, nil, errors.New("require asset options")
+	}
+
+	tag := opts[0].Tag
+	for _, opt := range opts[1:] {
+		if opt.Tag != tag {
+			return nil, nil, fmt.Errorf("mismatch release tag: %s, %s", tag, opt.Tag)
+		}
+	}
+
+	release, err := c.GetRelease(ctx, repo, tag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolutions := make([]*Resolution, 0, len(opts))
+	for _, opt := range opts {
+		resolution, err := ResolveAssets(release, opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		resolutions = append(resolutions, resolution)
+	}
+	return release, resolutions, nil
+}
+
+// ResolveAssets scores the assets of the release, and selects the best one,
+// the asset of the name template, or every asset matched the patterns of the options.
+// It returns the options error, and the selection error is in the resolution.
+func ResolveAssets(release *RepositoryRelease, opt *AssetOptions) (*Resolution, error) {
+	resolution := &Resolution{
+		opt:      opt,
+		Name:     opt.Name,
+		Regex:    opt.NameRegex,
+		Glob:     opt.NameGlob,
+		Template: opt.NameTemplate,
+		Exclude:  opt.Exclude,
+		OS:       opt.OS,
+		Arch:     opt.Arch,
+		Libc:     opt.Libc,
+	}
+
+	exclude, err := opt.excluder()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case opt.NameTemplate != "":
+		resolution.Libc = ""
+		candidates, names, err := templateCandidates(release, opt, exclude)
+		if err != nil {
+			return nil, err
+		}
+		sortCandidates(candidates)
+		resolution.Candidates = candidates
+		if len(candidates) > 0 && !candidates[0].Rejected {
+			resolution.Assets = []*ReleaseAsset{candidates[0].Asset}
+		} else {
+			resolution.Err = fmt.Errorf("not found asset: [template: %s, names: %s]",
+				opt.NameTemplate, strings.Join(names, ", "))
+		}
+
+	case opt.byPattern():
+		resolution.OS, resolution.Arch, resolution.Libc = "", "", ""
+		candidates, err := patternCandidates(release, opt, exclude)
+		if err != nil {
+			return nil, err
+		}
+		resolution.Candidates = candidates
+		for _, v := range candidates {
+			if !v.Rejected {
+				resolution.Assets = append(resolution.Assets, v.Asset)
+			}
+		}
+		if len(resolution.Assets) == 0 {
+			resolution.Err = fmt.Errorf("not found asset: [regex: %s, glob: %s]", opt.NameRegex, opt.NameGlob)
+		}
+
+	default:
+		resolution.Candidates = rankAssets(release, opt, exclude)
+		asset, err := selectAsset(resolution.Candidates, opt)
+		if err != nil {
+			resolution.Err = err
+		} else {
+			resolution.Assets = []*ReleaseAsset{asset}
+		}
+	}
+
+	resolution.Selected = make([]string, 0, len(resolution.Assets))
+	for _, v := range resolution.Assets {
+		resolution.Selected = append(resolution.Selected, v.GetName())
+	}
+	if resolution.Err != nil {
+		resolution.Error = resolution.Err.Error()
+	}
+	return resolution, nil
+}
+
+// patternCandidates returns every asset of the release with the exclude, regex and glob criteria.
+func patternCandidates(release *RepositoryRelease,
+	opt *AssetOptions,
+	exclude func(name string) string,
+) ([]*Candidate, error) {
+	var re *regexp.Regexp
+	if opt.NameRegex != "" {
+		var err error
+		if re, err = regexp.Compile(opt.NameRegex); err != nil {
+			return nil, fmt.Errorf("asset regex error: %w", err)
+		}
+	}
+	if _, err := path.Match(opt.NameGlob, ""); err != nil {
+		return nil, fmt.Errorf("asset glob error: %w", err)
+	}
+
+	candidates := make([]*Candidate, 0, len(release.Assets))
+	for _, asset := range release.Assets {
+		candidate := &Candidate{Asset: asset, Name: asset.GetName(), Size: asset.GetSize()}
+		candidate.add(excludeCriterion(asset.GetName(), exclude))
+		if re != nil {
+			matched := re.MatchString(asset.GetName())
+			candidate.add(&Criterion{Name: "regex", Matched: matched, Reject: !matched, Detail: opt.NameRegex})
+		}
+		if opt.NameGlob != "" {
+			matched, _ := path.Match(opt.NameGlob, asset.GetName())
+			candidate.add(&Criterion{Name: "glob", Matched: matched, Reject: !matched, Detail: opt.NameGlob})
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
@@ -0,0 +1,423 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iwaltgen/github-dl/pkg/platform"
+)
+
+// Criterion is the match result of an asset selection criterion.
+type Criterion struct {
+	Name    string `json:"name"`
+	Matched bool   `json:"matched"`
+	Score   int    `json:"score"`
+	// Reject excludes the asset from the selection.
+	Reject bool   `json:"reject,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Candidate is a release asset scored by the selection criteria.
+type Candidate struct {
+	Asset    *ReleaseAsset `json:"-"`
+	Name     string        `json:"name"`
+	Size     int           `json:"size"`
+	Score    int           `json:"score"`
+	Rejected bool          `json:"rejected"`
+	Criteria []*Criterion  `json:"criteria"`
+}
+
+func (c *Candidate) add(criterion *Criterion) {
+	c.Criteria = append(c.Criteria, criterion)
+	c.Score += criterion.Score
+	c.Rejected = c.Rejected || criterion.Reject
+}
+
+// AmbiguousError reports several best assets have the same score.
+type AmbiguousError struct {
+	Candidates []*Candidate
+}
+
+func (e *AmbiguousError) Error() string {
+	names := make([]string, 0, len(e.Candidates))
+	for _, v := range e.Candidates {
+		names = append(names, fmt.Sprintf("%s (score: %d)", v.Name, v.Score))
+	}
+	return "ambiguous assets: " + strings.Join(names, ", ")
+}
+
+// maxAmbiguous is the maximum number of candidates in an ambiguous error.
+const maxAmbiguous = 5
+
+// selection scores.
+const (
+	scoreToken     = 10
+	scoreSubstring = 3
+	scoreOS        = 8
+	scoreArch      = 6
+	scoreLibc      = 4
+	scoreFallback  = 1
+	scoreLoose     = 2
+	scoreUnknown   = -1
+)
+
+// extScores are scores of file extensions. (longer suffix first)
+var extScores = []struct {
+	ext   string
+	score int
+}{
+	{".tar.gz", 3}, {".tgz", 3}, {".zip", 2},
+	{".exe", 2}, {".appimage", 2},
+	{".tar.xz", 0}, {".tar.bz2", 0}, {".txz", 0}, {".tbz", 0}, {".7z", 0}, {".gz", 0}, {".xz", 0},
+	{".deb", -3}, {".rpm", -3}, {".apk", -3}, {".msi", -3}, {".pkg", -3}, {".dmg", -3},
+	{".snap", -3}, {".flatpak", -3}, {".txt", -1}, {".json", -1}, {".yaml", -1}, {".yml", -1},
+}
+
+// scoreRaw is the score of a name without a known extension, which is likely a binary.
+const scoreRaw = 2
+
+// noiseTokens are name tokens of checksum, signature and sbom files.
+var noiseTokens = map[string]bool{
+	"sha1": true, "sha256": true, "sha512": true, "md5": true,
+	"sha256sum": true, "sha256sums": true, "sha512sum": true, "sha512sums": true,
+	"checksum": true, "checksums": true,
+	"sig": true, "asc": true, "minisig": true, "pem": true, "crt": true, "cert": true, "pub": true,
+	"sbom": true, "spdx": true, "cdx": true, "intoto": true, "provenance": true,
+}
+
+// tokenize splits the lower case name into alphanumeric tokens.
+func tokenize(name string) []string {
+	return platform.Tokens(name)
+}
+
+// indexTokens returns the index of the keyword tokens sequence in the tokens, or -1.
+func indexTokens(tokens, keyword []string) int {
+	if len(keyword) == 0 {
+		return -1
+	}
+	for i := 0; i+len(keyword) <= len(tokens); i++ {
+		matched := true
+		for j, v := range keyword {
+			if tokens[i+j] != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+// assetName is a tokenized asset name, which remembers tokens used by criteria.
+type assetName struct {
+	lower  string
+	tokens []string
+	known  []bool
+}
+
+func newAssetName(name string) *assetName {
+	tokens := tokenize(name)
+	return &assetName{
+		lower:  strings.ToLower(name),
+		tokens: tokens,
+		known:  make([]bool, len(tokens)),
+	}
+}
+
+// matchToken reports whether the keyword is a tokens sequence of the name, and marks it known.
+func (n *assetName) matchToken(keyword string) bool {
+	keyTokens := tokenize(keyword)
+	i := indexTokens(n.tokens, keyTokens)
+	if i < 0 {
+		return false
+	}
+	for j := range keyTokens {
+		n.known[i+j] = true
+	}
+	return true
+}
+
+// matchAny returns the first keyword matched as tokens, or matched as a substring.
+func (n *assetName) matchAny(keywords []string) (keyword string, token bool) {
+	for _, v := range keywords {
+		if v != "" && n.matchToken(v) {
+			return v, true
+		}
+	}
+	for _, v := range keywords {
+		if v != "" && strings.Contains(n.lower, strings.ToLower(v)) {
+			return v, false
+		}
+	}
+	return "", false
+}
+
+// rankAssets scores every asset of the release by the keywords of the options.
+// candidates are sorted by the score, and rejected candidates are last.
+func rankAssets(release *RepositoryRelease, opt *AssetOptions, exclude func(name string) string) []*Candidate {
+	candidates := make([]*Candidate, 0, len(release.Assets))
+	for _, asset := range release.Assets {
+		candidates = append(candidates, scoreAsset(asset, opt, exclude))
+	}
+	sortCandidates(candidates)
+	return candidates
+}
+
+// sortCandidates sorts candidates by the score, and rejected candidates are last.
+func sortCandidates(candidates []*Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Rejected != candidates[j].Rejected {
+			return !candidates[i].Rejected
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+}
+
+func scoreAsset(asset *ReleaseAsset, opt *AssetOptions, exclude func(name string) string) *Candidate {
+	name := newAssetName(asset.GetName())
+	candidate := &Candidate{Asset: asset, Name: asset.GetName(), Size: asset.GetSize()}
+
+	parsed := platform.Parse(asset.GetName())
+	for i, v := range name.tokens {
+		name.known[i] = name.known[i] || platform.Keyword(v)
+	}
+
+	candidate.add(excludeCriterion(asset.GetName(), exclude))
+	candidate.add(nameCriterion(name, opt))
+	candidate.add(noiseCriterion(name, opt))
+	candidate.add(osCriterion(name, parsed, opt))
+	candidate.add(archCriterion(name, parsed, opt))
+	candidate.add(libcCriterion(parsed, opt))
+	candidate.add(extCriterion(name))
+	candidate.add(unknownCriterion(name))
+	return candidate
+}
+
+// excludeCriterion rejects the name matched an exclude pattern.
+func excludeCriterion(name string, exclude func(name string) string) *Criterion {
+	criterion := &Criterion{Name: "exclude"}
+	if pattern := exclude(name); pattern != "" {
+		criterion.Matched, criterion.Reject, criterion.Detail = true, true, pattern
+	}
+	return criterion
+}
+
+func nameCriterion(name *assetName, opt *AssetOptions) *Criterion {
+	criterion := &Criterion{Name: "name", Detail: opt.Name}
+	switch {
+	case name.matchToken(opt.Name):
+		criterion.Matched, criterion.Score = true, scoreToken
+	case strings.Contains(name.lower, strings.ToLower(opt.Name)):
+		criterion.Matched, criterion.Score = true, scoreSubstring
+	default:
+		criterion.Reject = true
+	}
+	return criterion
+}
+
+// noiseCriterion rejects checksum, signature and sbom files, unless the name keyword asks for them.
+func noiseCriterion(name *assetName, opt *AssetOptions) *Criterion {
+	criterion := &Criterion{Name: "noise"}
+	for _, v := range tokenize(opt.Name) {
+		if noiseTokens[v] {
+			return criterion
+		}
+	}
+
+	for i, v := range name.tokens {
+		if noiseTokens[v] {
+			name.known[i] = true
+			criterion.Matched, criterion.Reject, criterion.Detail = true, true, v
+		}
+	}
+	return criterion
+}
+
+// aliasCriterion matches the keyword or aliases as tokens, or returns the loose match.
+func aliasCriterion(name *assetName, title string, score int, keywords []string) (*Criterion, bool) {
+	criterion := &Criterion{Name: title}
+	keyword, token := name.matchAny(keywords)
+	if keyword != "" && token {
+		criterion.Matched, criterion.Score, criterion.Detail = true, score, keyword
+		return criterion, true
+	}
+	if keyword != "" {
+		criterion.Score, criterion.Detail = scoreLoose, keyword
+	}
+	return criterion, false
+}
+
+// osCriterion matches the parsed os of the name, and rejects the name of another os.
+// names without any os and arch keyword are for every os. (e.g. checksums)
+func osCriterion(name *assetName, parsed platform.Platform, opt *AssetOptions) *Criterion {
+	criterion, ok := aliasCriterion(name, "os", scoreOS, append([]string{opt.OS}, opt.OSAlias...))
+	if ok {
+		return criterion
+	}
+
+	switch parsed.OS {
+	case "":
+		// a loose match may be a part of another word.
+		criterion.Matched = criterion.Detail != ""
+		// a build of an arch is not for every os. (e.g. tool_amd64.deb)
+		criterion.Reject = !criterion.Matched && parsed.Arch != ""
+	case platform.OS(opt.OS):
+		criterion.Matched, criterion.Score, criterion.Detail = true, scoreOS, parsed.OS
+	default:
+		criterion.Score, criterion.Reject, criterion.Detail = 0, true, parsed.OS
+	}
+	return criterion
+}
+
+// archCriterion matches the parsed arch and variant of the name, and rejects the name of another arch.
+// an older arm variant is accepted with a lower score, and a macOS universal binary matches every arch.
+// without the variant of the options, the oldest variant is preferred because it runs on newer ones.
+// names without any arch keyword are for every arch.
+func archCriterion(name *assetName, parsed platform.Platform, opt *AssetOptions) *Criterion {
+	criterion, ok := aliasCriterion(name, "arch", scoreArch, append([]string{opt.Arch}, opt.ArchAlias...))
+	if ok {
+		// an arch variant keyword is matched by the variant below. (e.g. armv7 alias of arm)
+		if _, variant := platform.Arch(criterion.Detail); variant == "" {
+			return criterion
+		}
+		criterion = &Criterion{Name: criterion.Name}
+	}
+
+	arch, variant := platform.Arch(opt.Arch)
+	detail := strings.TrimSuffix(parsed.Arch+"/"+parsed.Variant, "/")
+	switch {
+	case parsed.Arch == "":
+		criterion.Matched = criterion.Detail != ""
+	case parsed.Arch == arch && variant != "" && variantRank(parsed.Variant) > variantRank(variant):
+		criterion.Score, criterion.Reject, criterion.Detail = 0, true, detail
+	case parsed.Arch == arch && variant != "" && parsed.Variant != "" && parsed.Variant != variant:
+		criterion.Matched, criterion.Score, criterion.Detail = true, scoreArch-1, detail
+	case parsed.Arch == arch && variant == "" && parsed.Variant != "":
+		criterion.Matched, criterion.Score, criterion.Detail = true, scoreArch-variantRank(parsed.Variant), detail
+	case parsed.Arch == arch:
+		criterion.Matched, criterion.Score, criterion.Detail = true, scoreArch, detail
+	case parsed.Arch == platform.Universal && platform.OS(opt.OS) == "darwin":
+		criterion.Matched, criterion.Score, criterion.Detail = true, scoreArch-1, detail
+	default:
+		criterion.Score, criterion.Reject, criterion.Detail = 0, true, detail
+	}
+	return criterion
+}
+
+// libcCriterion prefers the libc of the options, and rejects gnu builds for musl.
+// static, then musl builds are the fallback of gnu, and portable builds are preferred without the libc.
+// names without any libc keyword are neutral.
+func libcCriterion(parsed platform.Platform, opt *AssetOptions) *Criterion {
+	criterion := &Criterion{Name: "libc", Detail: parsed.Libc}
+	want := platform.Libc(opt.Libc)
+	switch {
+	case parsed.Libc == "":
+	case parsed.Libc == want:
+		criterion.Matched, criterion.Score = true, scoreLibc
+	case parsed.Libc == "gnu" && want == "musl":
+		criterion.Reject = true
+	case parsed.Libc == "static":
+		criterion.Matched, criterion.Score = true, scoreFallback+1
+	case parsed.Libc == "musl" || want == "msvc":
+		criterion.Matched, criterion.Score = true, scoreFallback
+	}
+	return criterion
+}
+
+// variantRank returns the rank of the arm variant from v5, or 0.
+func variantRank(variant string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(variant, "v"))
+	if err != nil || n < 5 {
+		return 0
+	}
+	return n - 5
+}
+
+func extCriterion(name *assetName) *Criterion {
+	criterion := &Criterion{Name: "ext", Score: scoreRaw}
+	for _, v := range extScores {
+		if strings.HasSuffix(name.lower, v.ext) {
+			criterion.Matched, criterion.Score, criterion.Detail = true, v.score, v.ext
+			name.matchToken(v.ext)
+			break
+		}
+	}
+	return criterion
+}
+
+// unknownCriterion penalizes tokens which are not matched by any criteria and not a version.
+// (e.g. tool-plugin for the tool keyword)
+func unknownCriterion(name *assetName) *Criterion {
+	criterion := &Criterion{Name: "unknown"}
+	var unknowns []string
+	for i, v := range name.tokens {
+		if name.known[i] || isVersionToken(v) {
+			continue
+		}
+		unknowns = append(unknowns, v)
+	}
+
+	criterion.Matched = len(unknowns) > 0
+	criterion.Score = scoreUnknown * len(unknowns)
+	criterion.Detail = strings.Join(unknowns, ",")
+	return criterion
+}
+
+func isVersionToken(v string) bool {
+	v = strings.TrimPrefix(v, "v")
+	if v == "" {
+		return false
+	}
+	for _, r := range v {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// selectAsset returns the best candidate of the ranked candidates.
+func selectAsset(candidates []*Candidate, opt *AssetOptions) (*ReleaseAsset, error) {
+	if len(candidates) == 0 || candidates[0].Rejected {
+		if opt.Libc != "" {
+			return nil, fmt.Errorf("not found asset: [name: %s, os: %s, arch: %s, libc: %s]", opt.Name, opt.OS, opt.Arch, opt.Libc)
+		}
+		return nil, fmt.Errorf("not found asset: [name: %s, os: %s, arch: %s]", opt.Name, opt.OS, opt.Arch)
+	}
+
+	best := candidates[0]
+	var ties []*Candidate
+	for _, v := range candidates {
+		if v.Rejected || v.Score != best.Score {
+			break
+		}
+		ties = append(ties, v)
+	}
+	if len(ties) > 1 {
+		if len(ties) > maxAmbiguous {
+			ties = ties[:maxAmbiguous]
+		}
+		return nil, &AmbiguousError{Candidates: ties}
+	}
+	return best.Asset, nil
+}
@@ -0,0 +1,283 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testRelease(names ...string) *RepositoryRelease {
+	release := &RepositoryRelease{}
+	for i := range names {
+		id, name := int64(i+1), names[i]
+		release.Assets = append(release.Assets, &ReleaseAsset{ID: &id, Name: &name})
+	}
+	return release
+}
+
+func TestSelectAsset(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets []string
+		opt    *AssetOptions
+		want   string
+	}{
+		{
+			name:   "arm does not match arm64",
+			assets: []string{"tool_linux_arm64.tar.gz", "tool_linux_arm.tar.gz", "tool_linux_amd64.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "arm"},
+			want:   "tool_linux_arm.tar.gz",
+		},
+		{
+			name:   "checksum is noise",
+			assets: []string{"tool_linux_amd64.tar.gz.sha256", "tool_linux_amd64.tar.gz", "checksums.txt"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"},
+			want:   "tool_linux_amd64.tar.gz",
+		},
+		{
+			name:   "sbom is noise",
+			assets: []string{"tool_linux_amd64.sbom.json", "tool_linux_amd64.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"},
+			want:   "tool_linux_amd64.tar.gz",
+		},
+		{
+			name:   "checksum keyword",
+			assets: []string{"tool_linux_amd64.tar.gz", "tool_1.0_checksums.txt"},
+			opt:    &AssetOptions{Name: "checksums", OS: "linux", Arch: "amd64"},
+			want:   "tool_1.0_checksums.txt",
+		},
+		{
+			name:   "archive over package",
+			assets: []string{"tool_linux_amd64.deb", "tool_linux_amd64.rpm", "tool_linux_amd64.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"},
+			want:   "tool_linux_amd64.tar.gz",
+		},
+		{
+			name:   "whole name token",
+			assets: []string{"tool-plugin_linux_amd64.tar.gz", "tool_linux_amd64.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"},
+			want:   "tool_linux_amd64.tar.gz",
+		},
+		{
+			name:   "target triple",
+			assets: []string{"rg-x86_64-pc-windows-msvc.zip", "rg-x86_64-apple-darwin.tar.gz", "rg-x86_64-unknown-linux-musl.tar.gz"},
+			opt:    &AssetOptions{Name: "rg", OS: "linux", Arch: "amd64"},
+			want:   "rg-x86_64-unknown-linux-musl.tar.gz",
+		},
+		{
+			name:   "Linux_x86_64",
+			assets: []string{"tool_Darwin_x86_64.tar.gz", "tool_Linux_i386.tar.gz", "tool_Linux_x86_64.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"},
+			want:   "tool_Linux_x86_64.tar.gz",
+		},
+		{
+			name:   "linux64",
+			assets: []string{"jq-win64.exe", "jq-osx-amd64", "jq-linux32", "jq-linux64"},
+			opt:    &AssetOptions{Name: "jq", OS: "linux", Arch: "amd64"},
+			want:   "jq-linux64",
+		},
+		{
+			name:   "win64",
+			assets: []string{"protoc-3.13.0-linux-x86_64.zip", "protoc-3.13.0-win32.zip", "protoc-3.13.0-win64.zip"},
+			opt:    &AssetOptions{Name: "protoc", OS: "windows", Arch: "amd64"},
+			want:   "protoc-3.13.0-win64.zip",
+		},
+		{
+			name:   "macOS-universal",
+			assets: []string{"tool-linux64.tar.gz", "tool-win64.zip", "tool-macOS-universal.zip"},
+			opt:    &AssetOptions{Name: "tool", OS: "darwin", Arch: "arm64"},
+			want:   "tool-macOS-universal.zip",
+		},
+		{
+			name:   "exact arch over universal",
+			assets: []string{"tool-macOS-universal.zip", "tool-macOS-arm64.zip"},
+			opt:    &AssetOptions{Name: "tool", OS: "darwin", Arch: "arm64"},
+			want:   "tool-macOS-arm64.zip",
+		},
+		{
+			name:   "armv7",
+			assets: []string{"tool_linux_armv6.tar.gz", "tool_linux_armv7.tar.gz", "tool_linux_arm64.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "armv7"},
+			want:   "tool_linux_armv7.tar.gz",
+		},
+		{
+			name:   "armv7 accepts armv6",
+			assets: []string{"tool_linux_armv6.tar.gz", "tool_linux_arm64.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "armv7"},
+			want:   "tool_linux_armv6.tar.gz",
+		},
+		{
+			name:   "arm prefers the oldest variant",
+			assets: []string{"tool_linux_armv7.tar.gz", "tool_linux_armv6.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "arm"},
+			want:   "tool_linux_armv6.tar.gz",
+		},
+		{
+			name:   "arm variant aliases prefer the oldest variant",
+			assets: []string{"tool_linux_armv7.tar.gz", "tool_linux_armv6.tar.gz", "tool_linux_armhf.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "arm", ArchAlias: []string{"armv6", "armv7", "armhf"}},
+			want:   "tool_linux_armv6.tar.gz",
+		},
+		{
+			name:   "windows alias",
+			assets: []string{"tool-darwin-amd64.zip", "tool-win-x64.zip"},
+			opt:    &AssetOptions{Name: "tool", OS: "windows", OSAlias: []string{"win", "win64"}, Arch: "amd64", ArchAlias: []string{"x64"}},
+			want:   "tool-win-x64.zip",
+		},
+		{
+			name:   "musl",
+			assets: []string{"t-x86_64-unknown-linux-gnu.tar.gz", "t-x86_64-unknown-linux-musl.tar.gz"},
+			opt:    &AssetOptions{Name: "t", OS: "linux", Arch: "amd64", Libc: "musl"},
+			want:   "t-x86_64-unknown-linux-musl.tar.gz",
+		},
+		{
+			name:   "gnu",
+			assets: []string{"t-x86_64-unknown-linux-gnu.tar.gz", "t-x86_64-unknown-linux-musl.tar.gz"},
+			opt:    &AssetOptions{Name: "t", OS: "linux", Arch: "amd64", Libc: "gnu"},
+			want:   "t-x86_64-unknown-linux-gnu.tar.gz",
+		},
+		{
+			name:   "static fallback of gnu",
+			assets: []string{"t-linux-amd64-static.tar.gz", "t-x86_64-unknown-linux-musl.tar.gz"},
+			opt:    &AssetOptions{Name: "t", OS: "linux", Arch: "amd64", Libc: "gnu"},
+			want:   "t-linux-amd64-static.tar.gz",
+		},
+		{
+			name:   "platform independent",
+			assets: []string{"tool.jar", "tool-sources.jar.asc"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"},
+			want:   "tool.jar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolution, err := ResolveAssets(testRelease(tt.assets...), tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resolution.Err != nil {
+				t.Fatalf("selection error = %v, want %s", resolution.Err, tt.want)
+			}
+			if got := resolution.Selected; !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("selected = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectAssetNotFound(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets []string
+		opt    *AssetOptions
+	}{
+		{
+			name:   "only checksum",
+			assets: []string{"tool_linux_amd64.tar.gz.sha256", "tool_checksums.txt"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"},
+		},
+		{
+			name:   "another os",
+			assets: []string{"gh_1.0.0_linux_amd64.tar.gz", "gh_1.0.0_macOS_amd64.tar.gz", "gh_1.0.0_checksums.txt"},
+			opt:    &AssetOptions{Name: "gh", OS: "freebsd", Arch: "amd64"},
+		},
+		{
+			name:   "arch build without an os",
+			assets: []string{"gh_1.0.0_amd64.deb", "gh_1.0.0_linux_amd64.tar.gz"},
+			opt:    &AssetOptions{Name: "gh", OS: "freebsd", Arch: "amd64"},
+		},
+		{
+			name:   "newer arm variant",
+			assets: []string{"tool_linux_armv7.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "armv6"},
+		},
+		{
+			name:   "newer arm variant alias",
+			assets: []string{"tool_linux_armv7.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "armv6", ArchAlias: []string{"armv6", "armv7"}},
+		},
+		{
+			name:   "gnu on musl",
+			assets: []string{"t-x86_64-unknown-linux-gnu.tar.gz"},
+			opt:    &AssetOptions{Name: "t", OS: "linux", Arch: "amd64", Libc: "musl"},
+		},
+		{
+			name:   "excluded",
+			assets: []string{"tool_linux_amd64.tar.gz"},
+			opt:    &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64", Exclude: []string{"*.tar.gz"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolution, err := ResolveAssets(testRelease(tt.assets...), tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resolution.Err == nil {
+				t.Errorf("selected = %v, want not found", resolution.Selected)
+			}
+		})
+	}
+}
+
+func TestSelectAssetAmbiguous(t *testing.T) {
+	release := testRelease("tool_linux_amd64.tar.gz", "tool_linux_amd64.tgz", "tool_linux_amd64.zip")
+	resolution, err := ResolveAssets(release, &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ambiguous *AmbiguousError
+	if !errors.As(resolution.Err, &ambiguous) {
+		t.Fatalf("selection error = %v, want ambiguous", resolution.Err)
+	}
+	var names []string
+	for _, v := range ambiguous.Candidates {
+		names = append(names, v.Name)
+	}
+	if want := []string{"tool_linux_amd64.tar.gz", "tool_linux_amd64.tgz"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ambiguous = %v, want %v", names, want)
+	}
+}
+
+func TestRankAssets(t *testing.T) {
+	release := testRelease(
+		"tool_linux_arm64.tar.gz.sha256",
+		"tool_linux_amd64.deb",
+		"tool_darwin_amd64.tar.gz",
+		"tool_linux_amd64.tar.gz",
+	)
+	candidates := rankAssets(release, &AssetOptions{Name: "tool", OS: "linux", Arch: "amd64"},
+		func(string) string { return "" })
+
+	var got []string
+	for _, v := range candidates {
+		if !v.Rejected {
+			got = append(got, v.Name)
+		}
+	}
+	if want := []string{"tool_linux_amd64.tar.gz", "tool_linux_amd64.deb"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("accepted candidates = %v, want %v", got, want)
+	}
+	if last := candidates[len(candidates)-1]; !last.Rejected {
+		t.Errorf("last candidate %s is not rejected", last.Name)
+	}
+}
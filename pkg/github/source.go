@@ -0,0 +1,85 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	ggithub "github.com/google/go-github/v32/github"
+	"github.com/reactivex/rxgo/v2"
+)
+
+// DownloadSourceArchive downloads a source archive of the repository at the ref.
+// The ref is a tag, branch or commit sha. If it is empty, the tag of the release
+// of the options is used.
+// first returns source archive info. (the size is unknown before the download)
+// second returns download progress info and install result, or error info use a stream.
+// third returns initialize error info.
+// The archive is extracted and picked by the options like a release asset,
+// but it is neither cached nor receipted, because a ref may move.
+func (c *Client) DownloadSourceArchive(ctx context.Context,
+	repo Repository,
+	format ArchiveFormat,
+	ref string,
+	opt *AssetOptions,
+) (*ReleaseAsset, rxgo.Observable, error) {
+	if err := repo.valid(); err != nil {
+		return nil, nil, err
+	}
+
+	ext, err := sourceArchiveExt(format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ref == "" {
+		release, err := c.GetRelease(ctx, repo, opt.Tag)
+		if err != nil {
+			return nil, nil, err
+		}
+		ref = release.GetTagName()
+	}
+
+	link, _, err := c.client.Repositories.GetArchiveLink(ctx, repo.Owner(), repo.Name(), format,
+		&ggithub.RepositoryContentGetOptions{Ref: ref}, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := fmt.Sprintf("%s-%s%s", repo.Name(), strings.ReplaceAll(ref, "/", "-"), ext)
+	asset := &ReleaseAsset{Name: &name}
+	if c.verbose {
+		// the query of the link has a signed token.
+		color.Cyan("source dl url:\t%s://%s%s", link.Scheme, link.Host, link.Path)
+	}
+
+	return asset, c.limit(ctx, c.downloadLink(ctx, link, repo, asset, opt)), nil
+}
+
+func sourceArchiveExt(format ArchiveFormat) (string, error) {
+	switch format {
+	case Tarball:
+		return ".tar.gz", nil
+	case Zipball:
+		return ".zip", nil
+	default:
+		return "", fmt.Errorf("unknown source archive format: %s", format)
+	}
+}
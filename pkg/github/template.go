@@ -0,0 +1,117 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the data of an asset name template.
+type TemplateData struct {
+	// Version is the release tag without the "v" prefix.
+	Version string
+	Tag     string
+	OS      string
+	Arch    string
+	// Ext is an archive extension with the dot, which is tried in the order of templateExts.
+	Ext string
+}
+
+// templateExts are extensions tried for the Ext field of a template.
+var templateExts = []string{".tar.gz", ".tgz", ".zip", ".tar.xz", ".txz", ".tar.bz2", ".gz", ".xz", ".exe", ""}
+
+var templateFuncs = template.FuncMap{
+	"title": strings.Title,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// replace is pipeline friendly. (e.g. {{.Arch | replace "amd64" "x86_64"}})
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+	// map replaces the value by key:value mappings, or returns it. (e.g. {{map .OS "darwin:macOS" "linux:Linux"}})
+	"map": func(value string, mappings ...string) string {
+		for _, v := range mappings {
+			kv := strings.SplitN(v, ":", 2)
+			if len(kv) == 2 && kv[0] == value {
+				return kv[1]
+			}
+		}
+		return value
+	},
+}
+
+// templateNames returns asset names of the template for the release, in the order of templateExts.
+func templateNames(release *RepositoryRelease, opt *AssetOptions) ([]string, error) {
+	tmpl, err := template.New("asset").Funcs(templateFuncs).Option("missingkey=error").Parse(opt.NameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("asset template error: %w", err)
+	}
+
+	data := &TemplateData{
+		Version: strings.TrimPrefix(release.GetTagName(), "v"),
+		Tag:     release.GetTagName(),
+		OS:      opt.OS,
+		Arch:    opt.Arch,
+	}
+
+	var names []string
+	found := map[string]bool{}
+	for _, ext := range templateExts {
+		data.Ext = ext
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return nil, fmt.Errorf("asset template error: %w", err)
+		}
+		if name := sb.String(); !found[name] {
+			found[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// templateCandidates returns every asset of the release with the exclude and template criteria.
+// the asset of the first template name is selected.
+func templateCandidates(release *RepositoryRelease,
+	opt *AssetOptions,
+	exclude func(name string) string,
+) ([]*Candidate, []string, error) {
+	names, err := templateNames(release, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := make([]*Candidate, 0, len(release.Assets))
+	for _, asset := range release.Assets {
+		candidate := &Candidate{Asset: asset, Name: asset.GetName(), Size: asset.GetSize()}
+		candidate.add(excludeCriterion(asset.GetName(), exclude))
+
+		criterion := &Criterion{Name: "template", Reject: true}
+		for i, v := range names {
+			if v == asset.GetName() {
+				// the earlier extension is preferred.
+				criterion.Matched, criterion.Reject, criterion.Score, criterion.Detail = true, false, len(names)-i, v
+				break
+			}
+		}
+		candidate.add(criterion)
+		candidates = append(candidates, candidate)
+	}
+	return candidates, names, nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Policy decides how existing destination files are handled on commit.
+type Policy int
+
+const (
+	// Overwrite replaces existing files.
+	Overwrite Policy = iota
+	// NoClobber keeps existing files and skips the staged ones.
+	NoClobber
+	// Backup keeps an existing file as `name.bak`, replacing a previous backup.
+	Backup
+	// NumberedBackup keeps an existing file as `name.bak.N` with the next number.
+	NumberedBackup
+)
+
+// CommitOptions are parameters to commit a stage.
+type CommitOptions struct {
+	Policy Policy
+	// ReplaceFolders replaces a folder which is in the way of a staged file,
+	// unless the policy is NoClobber.
+	ReplaceFolders bool
+}
+
+// CommitResult is the result of a commit.
+type CommitResult struct {
+	// Files are the committed file paths.
+	Files []string
+	// Skipped are the existing file paths kept by the no clobber policy.
+	Skipped []string
+	// Backups are the backup file paths of the replaced files.
+	Backups []string
+}
+
+// transaction records the changes of a commit to undo.
+// Existing files are moved aside before a staged file is moved in,
+// so a running executable is replaced safely.
+type transaction struct {
+	stage  *Stage
+	opt    *CommitOptions
+	result *CommitResult
+	undo   []func()
+	seq    int
+}
+
+func (t *transaction) move(rel string) error {
+	source := t.stage.Path(rel)
+	target := filepath.Join(t.stage.dest, rel)
+
+	if err := t.mkdir(filepath.Dir(target)); err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(target); err == nil {
+		if t.opt.Policy == NoClobber {
+			t.result.Skipped = append(t.result.Skipped, target)
+			return nil
+		}
+		if info.IsDir() && !t.opt.ReplaceFolders {
+			return fmt.Errorf("install `%s` error: destination is a folder", target)
+		}
+
+		if err := t.moveAside(target, info.IsDir()); err != nil {
+			return err
+		}
+	}
+
+	if err := t.rename(source, target); err != nil {
+		return fmt.Errorf("install `%s` error: %w", target, err)
+	}
+	t.result.Files = append(t.result.Files, target)
+	return nil
+}
+
+// moveAside moves an existing file to the backup path of the policy,
+// or to the stage to be removed with it.
+func (t *transaction) moveAside(target string, isDir bool) error {
+	backup := ""
+	if !isDir {
+		switch t.opt.Policy {
+		case Backup:
+			backup = target + ".bak"
+		case NumberedBackup:
+			backup = nextNumberedBackup(target)
+		}
+	}
+
+	if backup != "" {
+		if _, err := os.Lstat(backup); err == nil {
+			if err := t.discard(backup); err != nil {
+				return err
+			}
+		}
+		if err := t.rename(target, backup); err != nil {
+			return fmt.Errorf("backup `%s` error: %w", target, err)
+		}
+		t.result.Backups = append(t.result.Backups, backup)
+		return nil
+	}
+	return t.discard(target)
+}
+
+// discard moves an existing path into the stage, which is removed with the stage.
+func (t *transaction) discard(target string) error {
+	t.seq++
+	trash := filepath.Join(t.stage.dir, backupDir, strconv.Itoa(t.seq))
+	if err := t.rename(target, trash); err != nil {
+		return fmt.Errorf("replace `%s` error: %w", target, err)
+	}
+	return nil
+}
+
+func (t *transaction) rename(source, target string) error {
+	if err := os.Rename(source, target); err != nil {
+		return err
+	}
+	t.undo = append(t.undo, func() {
+		_ = os.Rename(target, source)
+	})
+	return nil
+}
+
+// mkdir creates the folder and remembers the top most created folder.
+func (t *transaction) mkdir(dir string) error {
+	missing := ""
+	for p := dir; ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil {
+			break
+		}
+		missing = p
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("mkdir `%s` error: %w", dir, err)
+	}
+	if missing != "" {
+		t.undo = append(t.undo, func() {
+			_ = os.RemoveAll(missing)
+		})
+	}
+	return nil
+}
+
+// rollback undoes the changes in reverse order.
+func (t *transaction) rollback() {
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	t.undo = nil
+}
+
+func nextNumberedBackup(target string) string {
+	for i := 1; ; i++ {
+		backup := fmt.Sprintf("%s.bak.%d", target, i)
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			return backup
+		}
+	}
+}
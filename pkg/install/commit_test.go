@@ -0,0 +1,172 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCommitPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		opt    *CommitOptions
+		want   map[string]string
+		backup []string
+	}{
+		{
+			name: "overwrite",
+			opt:  &CommitOptions{Policy: Overwrite},
+			want: map[string]string{"a": "v3"},
+		},
+		{
+			name: "no clobber",
+			opt:  &CommitOptions{Policy: NoClobber},
+			want: map[string]string{"a": "v1"},
+		},
+		{
+			name:   "backup",
+			opt:    &CommitOptions{Policy: Backup},
+			want:   map[string]string{"a": "v3", "a.bak": "v2"},
+			backup: []string{"a.bak"},
+		},
+		{
+			name:   "numbered backup",
+			opt:    &CommitOptions{Policy: NumberedBackup},
+			want:   map[string]string{"a": "v3", "a.bak.1": "v1", "a.bak.2": "v2"},
+			backup: []string{"a.bak.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := tempDir(t)
+			writeFile(t, filepath.Join(dest, "a"), "v1")
+
+			var result *CommitResult
+			for _, v := range []string{"v2", "v3"} {
+				stage := newTestStage(t, dest, map[string]string{"a": v})
+				var err error
+				if result, err = stage.Commit(tt.opt); err != nil {
+					t.Fatal(err)
+				}
+				stage.Discard()
+			}
+
+			if got := tree(t, dest); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tree = %v, want %v", got, tt.want)
+			}
+
+			var backups []string
+			for _, v := range tt.backup {
+				backups = append(backups, filepath.Join(dest, v))
+			}
+			if !reflect.DeepEqual(result.Backups, backups) {
+				t.Errorf("backups = %v, want %v", result.Backups, backups)
+			}
+		})
+	}
+}
+
+func TestCommitNoClobberSkipped(t *testing.T) {
+	dest := tempDir(t)
+	writeFile(t, filepath.Join(dest, "a"), "old-a")
+
+	stage := newTestStage(t, dest, map[string]string{"a": "new-a", "b": "new-b"})
+	result, err := stage.Commit(&CommitOptions{Policy: NoClobber})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage.Discard()
+
+	if want := []string{filepath.Join(dest, "a")}; !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("skipped = %v, want %v", result.Skipped, want)
+	}
+	if want := []string{filepath.Join(dest, "b")}; !reflect.DeepEqual(result.Files, want) {
+		t.Errorf("files = %v, want %v", result.Files, want)
+	}
+	want := map[string]string{"a": "old-a", "b": "new-b"}
+	if got := tree(t, dest); !reflect.DeepEqual(got, want) {
+		t.Errorf("tree = %v, want %v", got, want)
+	}
+}
+
+func TestCommitFolderInTheWay(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     *CommitOptions
+		wantErr bool
+		want    map[string]string
+	}{
+		{
+			name:    "fail",
+			opt:     &CommitOptions{},
+			wantErr: true,
+			want:    map[string]string{"a/": "", "a/x": "old-x"},
+		},
+		{
+			name: "replace folders",
+			opt:  &CommitOptions{ReplaceFolders: true},
+			want: map[string]string{"a": "new-a"},
+		},
+		{
+			name: "replace folders without backup",
+			opt:  &CommitOptions{Policy: Backup, ReplaceFolders: true},
+			want: map[string]string{"a": "new-a"},
+		},
+		{
+			name: "no clobber",
+			opt:  &CommitOptions{Policy: NoClobber, ReplaceFolders: true},
+			want: map[string]string{"a/": "", "a/x": "old-x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := tempDir(t)
+			writeFile(t, filepath.Join(dest, "a", "x"), "old-x")
+
+			stage := newTestStage(t, dest, map[string]string{"a": "new-a"})
+			_, err := stage.Commit(tt.opt)
+			stage.Discard()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("commit error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if got := tree(t, dest); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tree = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextNumberedBackup(t *testing.T) {
+	dest := tempDir(t)
+	target := filepath.Join(dest, "a")
+	for _, v := range []string{"a.bak.1", "a.bak.2", "a.bak.4"} {
+		writeFile(t, filepath.Join(dest, v), v)
+	}
+	if err := os.Mkdir(filepath.Join(dest, "a.bak.3"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := nextNumberedBackup(target), target+".bak.5"; got != want {
+		t.Errorf("next numbered backup = %s, want %s", got, want)
+	}
+}
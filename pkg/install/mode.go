@@ -0,0 +1,116 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// IsExecutable reports whether the file is an executable binary (ELF, Mach-O, PE)
+// or a script starting with a shebang.
+func IsExecutable(fpath string) (bool, error) {
+	file, err := os.Open(fpath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 8)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	return isExecutable(head[:n]), nil
+}
+
+func isExecutable(head []byte) bool {
+	magics := [][]byte{
+		[]byte("\x7fELF"),        // ELF
+		{0xfe, 0xed, 0xfa, 0xce}, // Mach-O 32-bit
+		{0xfe, 0xed, 0xfa, 0xcf}, // Mach-O 64-bit
+		{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit (little endian)
+		{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit (little endian)
+		[]byte("MZ"),             // PE
+		[]byte("#!"),             // shebang
+	}
+	for _, magic := range magics {
+		if bytes.HasPrefix(head, magic) {
+			return true
+		}
+	}
+
+	// Mach-O universal binary shares the magic with Java class files,
+	// the architecture count is much smaller than a class file version.
+	if len(head) >= 8 && bytes.HasPrefix(head, []byte{0xca, 0xfe, 0xba, 0xbe}) {
+		return binary.BigEndian.Uint32(head[4:8]) < 45
+	}
+	return false
+}
+
+// executableMode adds the executable bits where the read bits are set.
+func executableMode(mode os.FileMode) os.FileMode {
+	return mode | (mode&0444)>>2
+}
+
+// MarkExecutables adds the executable bits to the staged files detected as executables.
+func (s *Stage) MarkExecutables() error {
+	files, err := s.Files()
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range files {
+		fpath := s.Path(rel)
+		info, err := os.Stat(fpath)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&0111 != 0 {
+			continue
+		}
+
+		ok, err := IsExecutable(fpath)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := os.Chmod(fpath, executableMode(info.Mode().Perm())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Chmod sets the mode of the staged files.
+func (s *Stage) Chmod(mode os.FileMode) error {
+	files, err := s.Files()
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range files {
+		if err := os.Chmod(s.Path(rel), mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	stagePrefix = ".github-dl-stage-"
+	outputDir   = "out"
+	workDir     = "work"
+	backupDir   = "backup"
+
+	staleStageAge = 24 * time.Hour
+)
+
+// Stage is a temporary folder in the destination path.
+// Outputs are prepared in the stage, and moved into the destination
+// only when every step succeeds. The stage is on the same file system
+// as the destination, so the outputs are moved by rename.
+type Stage struct {
+	dest       string
+	dir        string
+	createdDst bool
+}
+
+// NewStage creates a stage folder in the destination path.
+func NewStage(dest string) (*Stage, error) {
+	_, err := os.Stat(dest)
+	createdDst := os.IsNotExist(err)
+	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("mkdir `%s` error: %w", dest, err)
+	}
+
+	removeStaleStages(dest)
+	dir, err := ioutil.TempDir(dest, stagePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("create stage error: %w", err)
+	}
+
+	for _, v := range []string{outputDir, workDir, backupDir} {
+		if err := os.Mkdir(filepath.Join(dir, v), os.ModePerm); err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, fmt.Errorf("create stage error: %w", err)
+		}
+	}
+
+	return &Stage{
+		dest:       dest,
+		dir:        dir,
+		createdDst: createdDst,
+	}, nil
+}
+
+// Dest returns the destination path.
+func (s *Stage) Dest() string {
+	return s.dest
+}
+
+// Path returns the staged output path of a path relative to the destination.
+func (s *Stage) Path(rel string) string {
+	return filepath.Join(s.dir, outputDir, rel)
+}
+
+// WorkDir returns a scratch folder of the stage, which is never committed.
+func (s *Stage) WorkDir() string {
+	return filepath.Join(s.dir, workDir)
+}
+
+// Files returns staged output file paths relative to the destination.
+func (s *Stage) Files() ([]string, error) {
+	root := filepath.Join(s.dir, outputDir)
+
+	var files []string
+	err := filepath.Walk(root, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, fpath)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Commit moves the staged outputs into the destination.
+// Existing destination files are handled by the options.
+// Every change is undone if any move fails.
+func (s *Stage) Commit(opt *CommitOptions) (*CommitResult, error) {
+	if opt == nil {
+		opt = &CommitOptions{}
+	}
+
+	files, err := s.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &transaction{stage: s, opt: opt, result: &CommitResult{}}
+	for _, rel := range files {
+		if err := tx.move(rel); err != nil {
+			tx.rollback()
+			return nil, err
+		}
+	}
+	return tx.result, nil
+}
+
+// Discard removes the stage folder and the destination created by the stage if it is empty.
+func (s *Stage) Discard() {
+	_ = os.RemoveAll(s.dir)
+	if s.createdDst {
+		_ = os.Remove(s.dest)
+	}
+}
+
+// removeStaleStages removes stage folders left by previous runs.
+// (e.g. a replaced executable which was running could not be removed on windows)
+// recent stages may belong to running installs, so they are kept.
+func removeStaleStages(dest string) {
+	matches, _ := filepath.Glob(filepath.Join(dest, stagePrefix+"*"))
+	for _, v := range matches {
+		info, err := os.Stat(v)
+		if err != nil || time.Since(info.ModTime()) < staleStageAge {
+			continue
+		}
+		_ = os.RemoveAll(v)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "github-dl-install-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func writeFile(t *testing.T, fpath, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(fpath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tree returns the files of the folder with the contents. folders end with "/".
+func tree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	ret := map[string]string{}
+	err := filepath.Walk(root, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil || fpath == root {
+			return err
+		}
+		rel, _ := filepath.Rel(root, fpath)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			ret[rel+"/"] = ""
+			return nil
+		}
+		b, err := ioutil.ReadFile(fpath)
+		ret[rel] = string(b)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func newTestStage(t *testing.T, dest string, files map[string]string) *Stage {
+	t.Helper()
+	stage, err := NewStage(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rel, content := range files {
+		writeFile(t, stage.Path(rel), content)
+	}
+	return stage
+}
+
+func TestStageCommit(t *testing.T) {
+	dest := tempDir(t)
+	writeFile(t, filepath.Join(dest, "a"), "old-a")
+
+	stage := newTestStage(t, dest, map[string]string{"a": "new-a", "b/x": "new-x"})
+	result, err := stage.Commit(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage.Discard()
+
+	want := map[string]string{"a": "new-a", "b/": "", "b/x": "new-x"}
+	if got := tree(t, dest); !reflect.DeepEqual(got, want) {
+		t.Errorf("tree = %v, want %v", got, want)
+	}
+
+	files := append([]string(nil), result.Files...)
+	sort.Strings(files)
+	wantFiles := []string{filepath.Join(dest, "a"), filepath.Join(dest, "b", "x")}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("files = %v, want %v", files, wantFiles)
+	}
+}
+
+func TestStageCommitRollback(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		dest   map[string]string
+	}{
+		{
+			name:   "overwrite",
+			policy: Overwrite,
+			dest:   map[string]string{"a": "old-a", "c/": ""},
+		},
+		{
+			name:   "backup",
+			policy: Backup,
+			dest:   map[string]string{"a": "old-a", "a.bak": "older-a", "c/": ""},
+		},
+		{
+			name:   "numbered backup",
+			policy: NumberedBackup,
+			dest:   map[string]string{"a": "old-a", "a.bak.1": "older-a", "c/": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := tempDir(t)
+			for rel, content := range tt.dest {
+				if rel[len(rel)-1] == '/' {
+					if err := os.MkdirAll(filepath.Join(dest, rel), os.ModePerm); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				writeFile(t, filepath.Join(dest, rel), content)
+			}
+
+			// "a" and "b/x" are committed before the folder "c" fails the commit.
+			stage := newTestStage(t, dest, map[string]string{"a": "new-a", "b/x": "new-x", "c": "new-c"})
+			if _, err := stage.Commit(&CommitOptions{Policy: tt.policy}); err == nil {
+				t.Fatal("commit error = nil, want destination is a folder")
+			}
+			stage.Discard()
+
+			if got := tree(t, dest); !reflect.DeepEqual(got, tt.dest) {
+				t.Errorf("tree = %v, want %v", got, tt.dest)
+			}
+		})
+	}
+}
+
+func TestStageDiscard(t *testing.T) {
+	dest := filepath.Join(tempDir(t), "bin")
+	stage := newTestStage(t, dest, map[string]string{"a": "new-a"})
+	stage.Discard()
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("stat created destination error = %v, want not exist", err)
+	}
+}
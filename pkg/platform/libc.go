@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"debug/elf"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// dynamic loaders of the C libraries.
+var (
+	gnuLoaders  = []string{"/lib*/ld-linux*.so.*", "/lib/*/ld-linux*.so.*", "/lib*/ld64.so.*"}
+	muslLoaders = []string{"/lib/ld-musl-*.so.1"}
+)
+
+// libcBinaries are host binaries linked against the C library of the host.
+var libcBinaries = []string{"/bin/sh"}
+
+// HostLibc returns the C library of the host, detected by the dynamic loader.
+// It returns msvc on windows, and empty if unknown or not applicable.
+func HostLibc() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "msvc"
+	case "linux":
+		return linuxLibc("/")
+	default:
+		return ""
+	}
+}
+
+// linuxLibc returns the C library of the root folder.
+// The loader of the shell is checked first, and then the installed loaders.
+// glibc loaders win, because glibc hosts may have the musl package installed.
+func linuxLibc(root string) string {
+	for _, v := range libcBinaries {
+		if libc := loaderLibc(interpreter(filepath.Join(root, v))); libc != "" {
+			return libc
+		}
+	}
+
+	if globAny(root, gnuLoaders) {
+		return "gnu"
+	}
+	if globAny(root, muslLoaders) {
+		return "musl"
+	}
+	return ""
+}
+
+// interpreter returns the dynamic loader path of the ELF binary, or empty.
+func interpreter(fpath string) string {
+	file, err := elf.Open(fpath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		data := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(data, 0); err != nil {
+			return ""
+		}
+		return strings.TrimRight(string(data), "\x00")
+	}
+	return ""
+}
+
+// loaderLibc returns the C library of the dynamic loader path, or empty.
+func loaderLibc(loader string) string {
+	name := filepath.Base(loader)
+	for _, v := range []struct {
+		patterns []string
+		libc     string
+	}{
+		{gnuLoaders, "gnu"},
+		{muslLoaders, "musl"},
+	} {
+		for _, pattern := range v.patterns {
+			if matched, _ := filepath.Match(filepath.Base(pattern), name); matched {
+				return v.libc
+			}
+		}
+	}
+	return ""
+}
+
+func globAny(root string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matches, _ := filepath.Glob(filepath.Join(root, pattern)); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Libc returns the libc name of the keyword. (e.g. gnu of glibc)
+// Unknown keywords are returned in lower case.
+func Libc(keyword string) string {
+	if v, ok := tokens[strings.ToLower(keyword)]; ok && v.Libc != "" {
+		return v.Libc
+	}
+	return strings.ToLower(keyword)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeRoot(t *testing.T, files ...string) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "github-dl-libc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(root) })
+
+	for _, v := range files {
+		fpath := filepath.Join(root, v)
+		if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fpath, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestLinuxLibcLoaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{
+			name:  "glibc with the musl package",
+			files: []string{"lib/ld-musl-x86_64.so.1", "lib64/ld-linux-x86-64.so.2"},
+			want:  "gnu",
+		},
+		{
+			name:  "debian multiarch with the musl package",
+			files: []string{"lib/ld-musl-aarch64.so.1", "lib/aarch64-linux-gnu/ld-linux-aarch64.so.1"},
+			want:  "gnu",
+		},
+		{name: "glibc", files: []string{"lib64/ld-linux-x86-64.so.2"}, want: "gnu"},
+		{name: "glibc ppc64le", files: []string{"lib64/ld64.so.2"}, want: "gnu"},
+		{name: "alpine", files: []string{"lib/ld-musl-x86_64.so.1"}, want: "musl"},
+		{name: "unknown", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := linuxLibc(makeRoot(t, tt.files...)); got != tt.want {
+				t.Errorf("linuxLibc() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinuxLibcShell(t *testing.T) {
+	loader := interpreter("/bin/sh")
+	want := loaderLibc(loader)
+	if want == "" {
+		t.Skipf("unknown loader of /bin/sh: %q", loader)
+	}
+
+	// the loader of the shell wins over the installed loaders of another libc.
+	other := "lib/ld-musl-x86_64.so.1"
+	if want == "musl" {
+		other = "lib64/ld-linux-x86-64.so.2"
+	}
+	root := makeRoot(t, other)
+
+	data, err := ioutil.ReadFile("/bin/sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "bin"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "bin", "sh"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := linuxLibc(root); got != want {
+		t.Errorf("linuxLibc() = %q, want %q of %s", got, want, loader)
+	}
+}
+
+func TestLoaderLibc(t *testing.T) {
+	tests := []struct {
+		loader string
+		want   string
+	}{
+		{loader: "/lib64/ld-linux-x86-64.so.2", want: "gnu"},
+		{loader: "/lib/ld-linux-armhf.so.3", want: "gnu"},
+		{loader: "/lib/ld-linux-aarch64.so.1", want: "gnu"},
+		{loader: "/lib64/ld64.so.2", want: "gnu"},
+		{loader: "/lib/ld-musl-x86_64.so.1", want: "musl"},
+		{loader: "/lib/ld-musl-armhf.so.1", want: "musl"},
+		{loader: "/system/bin/linker64", want: ""},
+		{loader: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := loaderLibc(tt.loader); got != tt.want {
+			t.Errorf("loaderLibc(%q) = %q, want %q", tt.loader, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Universal is the arch of macOS universal binaries, which run on every darwin arch.
+const Universal = "universal"
+
+// Platform is a target platform of a file name.
+// OS and Arch use GOOS and GOARCH names.
+type Platform struct {
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+	// Variant is the arch variant. (e.g. v6, v7 of arm)
+	Variant string `json:"variant,omitempty"`
+	// Libc is the C library. (gnu, musl, static, msvc)
+	Libc string `json:"libc,omitempty"`
+	// ABI is the calling convention. (e.g. eabihf of arm)
+	ABI string `json:"abi,omitempty"`
+}
+
+func (p Platform) String() string {
+	var parts []string
+	for _, v := range []string{p.OS, p.Arch, p.Variant, p.Libc, p.ABI} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// merge fills the empty fields with the other.
+func (p *Platform) merge(other Platform) {
+	if p.OS == "" {
+		p.OS = other.OS
+	}
+	if p.Arch == "" {
+		p.Arch = other.Arch
+	}
+	if p.Variant == "" {
+		p.Variant = other.Variant
+	}
+	if p.Libc == "" {
+		p.Libc = other.Libc
+	}
+	if p.ABI == "" {
+		p.ABI = other.ABI
+	}
+}
+
+// tokens are name tokens of platforms. The empty platform is a vendor of target triples.
+var tokens = map[string]Platform{
+	// os
+	"linux":     {OS: "linux"},
+	"linux32":   {OS: "linux", Arch: "386"},
+	"linux64":   {OS: "linux", Arch: "amd64"},
+	"darwin":    {OS: "darwin"},
+	"macos":     {OS: "darwin"},
+	"macosx":    {OS: "darwin"},
+	"osx":       {OS: "darwin"},
+	"mac":       {OS: "darwin"},
+	"apple":     {OS: "darwin"},
+	"windows":   {OS: "windows"},
+	"win":       {OS: "windows"},
+	"win32":     {OS: "windows", Arch: "386"},
+	"win64":     {OS: "windows", Arch: "amd64"},
+	"mingw":     {OS: "windows", Libc: "gnu"},
+	"mingw32":   {OS: "windows", Libc: "gnu"},
+	"mingw64":   {OS: "windows", Libc: "gnu"},
+	"freebsd":   {OS: "freebsd"},
+	"openbsd":   {OS: "openbsd"},
+	"netbsd":    {OS: "netbsd"},
+	"dragonfly": {OS: "dragonfly"},
+	"solaris":   {OS: "solaris"},
+	"illumos":   {OS: "illumos"},
+	"android":   {OS: "android"},
+	"ios":       {OS: "ios"},
+	"aix":       {OS: "aix"},
+
+	// arch
+	"amd64":       {Arch: "amd64"},
+	"x64":         {Arch: "amd64"},
+	"64bit":       {Arch: "amd64"},
+	"386":         {Arch: "386"},
+	"i386":        {Arch: "386"},
+	"i486":        {Arch: "386"},
+	"i586":        {Arch: "386"},
+	"i686":        {Arch: "386"},
+	"x86":         {Arch: "386"},
+	"ia32":        {Arch: "386"},
+	"32bit":       {Arch: "386"},
+	"arm64":       {Arch: "arm64"},
+	"arm64e":      {Arch: "arm64"},
+	"aarch64":     {Arch: "arm64"},
+	"armv8":       {Arch: "arm64"},
+	"arm":         {Arch: "arm"},
+	"arm32":       {Arch: "arm"},
+	"armv5":       {Arch: "arm", Variant: "v5"},
+	"armv5l":      {Arch: "arm", Variant: "v5"},
+	"armv5te":     {Arch: "arm", Variant: "v5"},
+	"armv6":       {Arch: "arm", Variant: "v6"},
+	"armv6l":      {Arch: "arm", Variant: "v6"},
+	"armv6hf":     {Arch: "arm", Variant: "v6", ABI: "eabihf"},
+	"armv7":       {Arch: "arm", Variant: "v7"},
+	"armv7l":      {Arch: "arm", Variant: "v7"},
+	"armv7a":      {Arch: "arm", Variant: "v7"},
+	"armv7hf":     {Arch: "arm", Variant: "v7", ABI: "eabihf"},
+	"armhf":       {Arch: "arm", Variant: "v7", ABI: "eabihf"},
+	"armel":       {Arch: "arm", Variant: "v5", ABI: "eabi"},
+	"ppc64le":     {Arch: "ppc64le"},
+	"powerpc64le": {Arch: "ppc64le"},
+	"ppc64":       {Arch: "ppc64"},
+	"powerpc64":   {Arch: "ppc64"},
+	"s390x":       {Arch: "s390x"},
+	"mips":        {Arch: "mips"},
+	"mipsle":      {Arch: "mipsle"},
+	"mipsel":      {Arch: "mipsle"},
+	"mips64":      {Arch: "mips64"},
+	"mips64le":    {Arch: "mips64le"},
+	"mips64el":    {Arch: "mips64le"},
+	"riscv64":     {Arch: "riscv64"},
+	"riscv64gc":   {Arch: "riscv64"},
+	"loong64":     {Arch: "loong64"},
+	"universal":   {OS: "darwin", Arch: Universal},
+	"universal2":  {OS: "darwin", Arch: Universal},
+
+	// libc and abi
+	"gnu":         {Libc: "gnu"},
+	"glibc":       {Libc: "gnu"},
+	"musl":        {Libc: "musl"},
+	"static":      {Libc: "static"},
+	"msvc":        {Libc: "msvc"},
+	"eabi":        {ABI: "eabi"},
+	"eabihf":      {ABI: "eabihf"},
+	"gnueabi":     {Libc: "gnu", ABI: "eabi"},
+	"gnueabihf":   {Libc: "gnu", ABI: "eabihf"},
+	"musleabi":    {Libc: "musl", ABI: "eabi"},
+	"musleabihf":  {Libc: "musl", ABI: "eabihf"},
+	"gnux32":      {Libc: "gnu", ABI: "x32"},
+	"androideabi": {OS: "android", ABI: "eabi"},
+
+	// vendor
+	"unknown": {},
+	"pc":      {},
+	"none":    {},
+}
+
+// joinedKeywords are keywords with separators, which are joined into tokens.
+var joinedKeywords = []struct{ keyword, token string }{
+	{"x86_64", "amd64"},
+	{"x86-64", "amd64"},
+	{"aarch_64", "arm64"},
+	{"mac_os", "macos"},
+	{"mac-os", "macos"},
+}
+
+// replacer joins keywords which have separators before the name is split into tokens.
+var replacer = func() *strings.Replacer {
+	var oldnew []string
+	for _, v := range joinedKeywords {
+		oldnew = append(oldnew, v.keyword, v.token)
+	}
+	return strings.NewReplacer(oldnew...)
+}()
+
+var tokenPattern = regexp.MustCompile(`[a-z0-9]+`)
+
+// Tokens splits the lower case name into alphanumeric tokens,
+// keeping keywords with separators. (e.g. x86_64)
+func Tokens(name string) []string {
+	return tokenPattern.FindAllString(replacer.Replace(strings.ToLower(name)), -1)
+}
+
+// osRefinements are the os of the more specific os keywords. (e.g. x86_64-linux-android)
+var osRefinements = map[string]string{
+	"android": "linux",
+}
+
+// Parse parses the platform of a file name.
+// It understands target triples (e.g. x86_64-unknown-linux-musl, aarch64-apple-darwin)
+// and common aliases (e.g. Linux_x86_64, linux64, macOS-universal, win64).
+// The first keyword of a field wins, except a more specific os, and missing fields are empty.
+func Parse(name string) Platform {
+	var p Platform
+	for _, token := range Tokens(name) {
+		v, ok := tokens[token]
+		if !ok {
+			continue
+		}
+		if osRefinements[v.OS] == p.OS && p.OS != "" {
+			p.OS = v.OS
+		}
+		p.merge(v)
+	}
+	return p
+}
+
+// builtin aliases are common spellings of GOOS and GOARCH names in asset names.
+// every alias is a keyword of the parser, so a loose match in the name of another platform is rejected. (e.g. win of darwin)
+// vendor keywords are left to the parser. (e.g. apple of aarch64-apple-darwin)
+var (
+	builtinOSAliases = map[string][]string{
+		"linux":   {"linux32", "linux64"},
+		"windows": {"win", "win32", "win64", "mingw"},
+		"darwin":  {"macos", "macosx", "osx", "mac"},
+	}
+	builtinArchAliases = map[string][]string{
+		"amd64": {"x86_64", "x64", "64bit"},
+		"386":   {"i386", "i586", "i686", "x86", "ia32", "32bit"},
+		"arm64": {"aarch64", "armv8"},
+		"arm":   {"arm32", "armv5", "armv6", "armv7", "armhf", "armel"},
+	}
+)
+
+// Aliases returns copies of the built-in os and arch aliases.
+func Aliases() (osAliases, archAliases map[string][]string) {
+	return copyAliases(builtinOSAliases), copyAliases(builtinArchAliases)
+}
+
+func copyAliases(aliases map[string][]string) map[string][]string {
+	ret := make(map[string][]string, len(aliases))
+	for k, v := range aliases {
+		ret[k] = append([]string(nil), v...)
+	}
+	return ret
+}
+
+// Keyword reports whether the token is a platform keyword.
+func Keyword(token string) bool {
+	_, ok := tokens[strings.ToLower(token)]
+	return ok
+}
+
+// OS returns the GOOS name of the os keyword. Unknown keywords are returned in lower case.
+func OS(keyword string) string {
+	if v, ok := tokens[strings.ToLower(keyword)]; ok && v.OS != "" {
+		return v.OS
+	}
+	return strings.ToLower(keyword)
+}
+
+// Arch returns the GOARCH name and variant of the arch keyword.
+// Unknown keywords are returned in lower case.
+func Arch(keyword string) (arch, variant string) {
+	lower := replacer.Replace(strings.ToLower(keyword))
+	if v, ok := tokens[lower]; ok && v.Arch != "" {
+		return v.Arch, v.Variant
+	}
+	return lower, ""
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2020 iwaltgen
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Platform
+	}{
+		// target triples
+		{"ripgrep-12.1.1-x86_64-unknown-linux-musl.tar.gz", Platform{OS: "linux", Arch: "amd64", Libc: "musl"}},
+		{"ripgrep-12.1.1-x86_64-unknown-linux-gnu.tar.gz", Platform{OS: "linux", Arch: "amd64", Libc: "gnu"}},
+		{"tool-aarch64-apple-darwin.tar.gz", Platform{OS: "darwin", Arch: "arm64"}},
+		{"tool-x86_64-pc-windows-msvc.zip", Platform{OS: "windows", Arch: "amd64", Libc: "msvc"}},
+		{"tool-i686-pc-windows-gnu.zip", Platform{OS: "windows", Arch: "386", Libc: "gnu"}},
+		{"tool-x86_64-w64-mingw32.zip", Platform{OS: "windows", Arch: "amd64", Libc: "gnu"}},
+		{"tool-armv7-unknown-linux-gnueabihf.tar.gz", Platform{OS: "linux", Arch: "arm", Variant: "v7", Libc: "gnu", ABI: "eabihf"}},
+		{"tool-arm-unknown-linux-musleabi.tar.gz", Platform{OS: "linux", Arch: "arm", Libc: "musl", ABI: "eabi"}},
+		{"tool-x86_64-unknown-linux-gnux32.tar.gz", Platform{OS: "linux", Arch: "amd64", Libc: "gnu", ABI: "x32"}},
+		{"tool-aarch64-linux-android.tar.gz", Platform{OS: "android", Arch: "arm64"}},
+		{"tool-armv7-linux-androideabi.tar.gz", Platform{OS: "android", Arch: "arm", Variant: "v7", ABI: "eabi"}},
+
+		// common aliases
+		{"tool_Linux_x86_64.tar.gz", Platform{OS: "linux", Arch: "amd64"}},
+		{"tool_0.1.0_Darwin_arm64.tar.gz", Platform{OS: "darwin", Arch: "arm64"}},
+		{"tool-linux64", Platform{OS: "linux", Arch: "amd64"}},
+		{"tool-linux32", Platform{OS: "linux", Arch: "386"}},
+		{"tool-win64.zip", Platform{OS: "windows", Arch: "amd64"}},
+		{"tool-win32.zip", Platform{OS: "windows", Arch: "386"}},
+		{"tool-windows-386.exe", Platform{OS: "windows", Arch: "386"}},
+		{"tool-macOS-universal.zip", Platform{OS: "darwin", Arch: Universal}},
+		{"tool-universal2-apple-darwin.tar.gz", Platform{OS: "darwin", Arch: Universal}},
+		{"tool_Mac_OS_X_x86-64.tar.gz", Platform{OS: "darwin", Arch: "amd64"}},
+		{"tool_osx_aarch_64.zip", Platform{OS: "darwin", Arch: "arm64"}},
+
+		// arm variants
+		{"tool_linux_armv6.tar.gz", Platform{OS: "linux", Arch: "arm", Variant: "v6"}},
+		{"tool_linux_armv7.tar.gz", Platform{OS: "linux", Arch: "arm", Variant: "v7"}},
+		{"tool-armv7l-linux-gnueabihf", Platform{OS: "linux", Arch: "arm", Variant: "v7", Libc: "gnu", ABI: "eabihf"}},
+		{"tool-linux-armhf.tar.gz", Platform{OS: "linux", Arch: "arm", Variant: "v7", ABI: "eabihf"}},
+		{"tool-linux-armel", Platform{OS: "linux", Arch: "arm", Variant: "v5", ABI: "eabi"}},
+		{"tool_linux_arm64.tar.gz", Platform{OS: "linux", Arch: "arm64"}},
+
+		// the first keyword of a field wins.
+		{"tool_linux_amd64_arm64.tar.gz", Platform{OS: "linux", Arch: "amd64"}},
+		{"tool-linux64-arm64", Platform{OS: "linux", Arch: "amd64"}},
+		{"tool-darwin-linux", Platform{OS: "darwin"}},
+
+		// noise and names without a platform
+		{"tool_1.0_linux_amd64.sbom.json", Platform{OS: "linux", Arch: "amd64"}},
+		{"tool_1.0_linux_arm64.deb", Platform{OS: "linux", Arch: "arm64"}},
+		{"tool_1.0_checksums.txt", Platform{}},
+		{"tool_darwin_all.tar.gz", Platform{OS: "darwin"}},
+		{"tool.jar", Platform{}},
+
+		// a part of another word is not a keyword.
+		{"emacs-macro-twin.tar.gz", Platform{}},
+		{"armory-linuxkit.tar.gz", Platform{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %#v, want %#v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"tool_Linux_x86_64.tar.gz", []string{"tool", "linux", "amd64", "tar", "gz"}},
+		{"tool-x86-64-Mac-OS", []string{"tool", "amd64", "macos"}},
+		{"tool-1.2.3-aarch_64", []string{"tool", "1", "2", "3", "arm64"}},
+	}
+
+	for _, tt := range tests {
+		if got := Tokens(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokens(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOS(t *testing.T) {
+	for keyword, want := range map[string]string{
+		"linux":   "linux",
+		"macOS":   "darwin",
+		"osx":     "darwin",
+		"win":     "windows",
+		"Windows": "windows",
+		"plan9":   "plan9",
+	} {
+		if got := OS(keyword); got != want {
+			t.Errorf("OS(%q) = %q, want %q", keyword, got, want)
+		}
+	}
+}
+
+func TestArch(t *testing.T) {
+	tests := []struct {
+		keyword string
+		arch    string
+		variant string
+	}{
+		{"amd64", "amd64", ""},
+		{"x86_64", "amd64", ""},
+		{"X86-64", "amd64", ""},
+		{"aarch64", "arm64", ""},
+		{"arm", "arm", ""},
+		{"armv6", "arm", "v6"},
+		{"armv7l", "arm", "v7"},
+		{"i686", "386", ""},
+		{"sparc64", "sparc64", ""},
+	}
+
+	for _, tt := range tests {
+		arch, variant := Arch(tt.keyword)
+		if arch != tt.arch || variant != tt.variant {
+			t.Errorf("Arch(%q) = %q, %q, want %q, %q", tt.keyword, arch, variant, tt.arch, tt.variant)
+		}
+	}
+}
+
+func TestAliases(t *testing.T) {
+	osAliases, archAliases := Aliases()
+	for name, aliases := range osAliases {
+		for _, v := range aliases {
+			if got := OS(v); got != name {
+				t.Errorf("OS(%q) = %q, want %q", v, got, name)
+			}
+		}
+	}
+	for name, aliases := range archAliases {
+		for _, v := range aliases {
+			if got, _ := Arch(v); got != name {
+				t.Errorf("Arch(%q) = %q, want %q", v, got, name)
+			}
+		}
+	}
+
+	// vendor keywords are left to the parser.
+	for _, v := range []string{"apple", "unknown", "pc"} {
+		for name, aliases := range osAliases {
+			for _, alias := range aliases {
+				if alias == v {
+					t.Errorf("os alias %s of %s", v, name)
+				}
+			}
+		}
+		for name, aliases := range archAliases {
+			for _, alias := range aliases {
+				if alias == v {
+					t.Errorf("arch alias %s of %s", v, name)
+				}
+			}
+		}
+	}
+
+	osAliases["darwin"][0] = "changed"
+	if again, _ := Aliases(); again["darwin"][0] == "changed" {
+		t.Error("Aliases() returns the built-in table")
+	}
+}